@@ -40,6 +40,9 @@ func TestWtAddListRemoveFlow(t *testing.T) {
 		t.Skip("git not installed; skipping integration test")
 	}
 
+	// wt add registers the repository in the global git config
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
 	repoRoot, err := findRepoRoot()
 	if err != nil {
 		t.Fatalf("failed to find repo root: %v", err)
@@ -2,10 +2,15 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/smoerfugl/wt/internal/models"
 	"github.com/smoerfugl/wt/internal/services"
 	"github.com/smoerfugl/wt/internal/utils"
 )
@@ -19,15 +24,90 @@ type AddCommand struct {
 	execCommands []*utils.Command // Commands to execute after worktree creation
 	worktreePath string
 	verbose      bool
+	// keepOnFailure leaves a partially created worktree in place for inspection
+	keepOnFailure bool
+	// carry moves uncommitted changes from the current worktree into the new one
+	carry          bool
+	carryUntracked bool
+	// remote selects the remote to track when the branch only exists on remotes
+	remote string
+	input  io.Reader // Source of answers to interactive prompts; nil never prompts
+	out    io.Writer // Where progress is reported; nil is stdout
+	// prNumber checks out pull/merge request prNumber as branch pr/<prNumber>
+	prNumber int
+	refresh  bool
+	// orphan creates an unborn branch; detach checks out a commit without a branch
+	orphan bool
+	detach bool
+	// lock locks the new worktree, with an optional reason
+	lock       bool
+	lockReason string
+	// sparseDirs and sparseProfiles limit the checkout to some directories
+	sparseDirs     []string
+	sparseProfiles []string
+	// noSubmodules leaves the submodules of the new worktree uninitialized
+	noSubmodules bool
+	// dirName overrides the directory name derived from the branch name
+	dirName string
+	// repos creates the worktree in these registered repositories or groups instead
+	repos []string
+}
+
+// AddOptions holds the settings for the 'wt add' command
+type AddOptions struct {
+	CreateBranch  bool             // Create a new branch (-b)
+	Verbose       bool             // Verbose output
+	BranchName    string           // Branch or commit to check out, or the new branch name
+	StartPoint    string           // Start point of a new branch
+	ExecCommands  []*utils.Command // Commands to execute after worktree creation
+	KeepOnFailure bool             // Do not roll back when a step fails
+	Carry         bool             // Move uncommitted changes into the new worktree
+	Untracked     bool             // With Carry, also move untracked files
+	Remote        string           // Remote to track for branches that only exist on remotes
+	PRNumber      int              // Pull/merge request to check out as pr/<number>
+	Refresh       bool             // With PRNumber, update an existing pull request worktree
+	Orphan        bool             // Create BranchName as an orphan branch without history
+	Detach        bool             // Check out BranchName (a commit) with a detached HEAD
+	Lock          bool             // Lock the new worktree
+	LockReason    string           // With Lock, why the worktree is locked
+	Sparse        []string         // Only check out these directories (cone-mode sparse-checkout)
+	SparseProfile []string         // Only check out the directories of these wt.sparse.<name> profiles
+	NoSubmodules  bool             // Do not initialize submodules
+	Name          string           // Directory name to use instead of the branch name's slug
+	Repos         []string         // Registered repositories or groups to create the worktree in
 }
 
 // NewAddCommand creates a new AddCommand instance
 func NewAddCommand(gitService *services.GitService) *AddCommand {
 	return &AddCommand{
 		gitService: gitService,
+		input:      os.Stdin,
 	}
 }
 
+// SetOutput sets where progress is reported
+func (ac *AddCommand) SetOutput(out io.Writer) {
+	ac.out = out
+}
+
+// output returns where progress is reported: stdout unless SetOutput was called
+func (ac *AddCommand) output() io.Writer {
+	if ac.out == nil {
+		return os.Stdout
+	}
+	return ac.out
+}
+
+// SetInput sets where answers to prompts are read from; nil fails instead of prompting
+func (ac *AddCommand) SetInput(input io.Reader) {
+	ac.input = input
+}
+
+// WorktreePath returns the path of the worktree, once Execute has determined it
+func (ac *AddCommand) WorktreePath() string {
+	return ac.worktreePath
+}
+
 // SetCreateBranch sets whether to create a new branch
 func (ac *AddCommand) SetCreateBranch(createBranch bool) {
 	ac.createBranch = createBranch
@@ -53,62 +133,348 @@ func (ac *AddCommand) SetWorktreePath(path string) {
 	ac.worktreePath = path
 }
 
+// SetDirName sets the worktree directory name to use instead of the branch name's slug
+func (ac *AddCommand) SetDirName(name string) {
+	ac.dirName = name
+}
+
 // SetVerbose sets verbose output mode
 func (ac *AddCommand) SetVerbose(verbose bool) {
 	ac.verbose = verbose
 }
 
-// Execute runs the add command
+// SetKeepOnFailure sets whether a failed add leaves its partial result in place
+func (ac *AddCommand) SetKeepOnFailure(keepOnFailure bool) {
+	ac.keepOnFailure = keepOnFailure
+}
+
+// SetCarry sets whether uncommitted changes of the current worktree are moved
+// into the new worktree, optionally including untracked files
+func (ac *AddCommand) SetCarry(carry, includeUntracked bool) {
+	ac.carry = carry
+	ac.carryUntracked = includeUntracked
+}
+
+// SetRemote sets the remote whose branch is tracked when the branch to check out
+// exists on several remotes but not locally
+func (ac *AddCommand) SetRemote(remote string) {
+	ac.remote = remote
+}
+
+// SetPullRequest checks out pull (GitHub) or merge (GitLab) request number as
+// local branch pr/<number>. With refresh, an existing worktree for the pull
+// request is updated to its latest head instead.
+func (ac *AddCommand) SetPullRequest(number int, refresh bool) {
+	ac.prNumber = number
+	ac.refresh = refresh
+}
+
+// SetOrphan sets whether the branch is created as an orphan branch with no history
+func (ac *AddCommand) SetOrphan(orphan bool) {
+	ac.orphan = orphan
+}
+
+// SetDetach sets whether the ref is checked out with a detached HEAD
+func (ac *AddCommand) SetDetach(detach bool) {
+	ac.detach = detach
+}
+
+// SetLock sets whether the new worktree is locked, and why
+func (ac *AddCommand) SetLock(lock bool, reason string) {
+	ac.lock = lock
+	ac.lockReason = reason
+}
+
+// SetSparse limits the new worktree to dirs and the directories of the named sparse profiles
+func (ac *AddCommand) SetSparse(dirs, profiles []string) {
+	ac.sparseDirs = dirs
+	ac.sparseProfiles = profiles
+}
+
+// SetNoSubmodules sets whether to skip initializing submodules in the new worktree
+func (ac *AddCommand) SetNoSubmodules(noSubmodules bool) {
+	ac.noSubmodules = noSubmodules
+}
+
+// SetRepos creates the worktree in each of these registered repositories or
+// groups of them instead of the current repository
+func (ac *AddCommand) SetRepos(repos []string) {
+	ac.repos = repos
+}
+
+// Execute runs the add command.
+// It is transactional: if any step fails, the worktree and any branch created
+// by wt are removed again, unless keepOnFailure is set.
 func (ac *AddCommand) Execute(repoPath string) error {
+	if len(ac.repos) > 0 {
+		return ac.executeRepos()
+	}
+	if err := ac.prepare(repoPath); err != nil {
+		return err
+	}
+
+	tx := &transaction{out: ac.output()}
+	if err := ac.execute(repoPath, tx); err != nil {
+		return ac.abort(tx, err, "worktree at "+ac.worktreePath)
+	}
+	autoRegister(ac.gitService, repoPath, ac.output())
+	return nil
+}
+
+// executeRepos creates the same branch and worktree in every repository named by
+// ac.repos. The repositories share one transaction, so if any of them fails the
+// worktrees already created in the others are removed as well.
+func (ac *AddCommand) executeRepos() error {
+	if ac.prNumber > 0 || ac.carry || ac.detach || len(ac.sparseDirs) > 0 || len(ac.sparseProfiles) > 0 || ac.worktreePath != "" {
+		return fmt.Errorf("--repos cannot be used with --pr, --carry, --detach, --sparse or --sparse-profile")
+	}
+	repos, err := resolveRepos(ac.gitService, ac.repos)
+	if err != nil {
+		return err
+	}
+	// Check the branch name everywhere before changing anything
+	for _, repo := range repos {
+		if err := ac.prepare(repo.Path); err != nil {
+			return fmt.Errorf("%s: %w", repo.Name, err)
+		}
+	}
+
+	tx := &transaction{out: ac.output()}
+	names := make([]string, len(repos))
+	var copies []*AddCommand
+	for i, repo := range repos {
+		names[i] = repo.Name
+		fmt.Fprintf(ac.output(), "==> %s (%s)\n", repo.Name, repo.Path)
+		repoAdd := ac.forRepo()
+		copies = append(copies, repoAdd)
+		if err := repoAdd.execute(repo.Path, tx); err != nil {
+			return ac.abort(tx, fmt.Errorf("%s: %w", repo.Name, err), "worktrees", copies...)
+		}
+	}
+	fmt.Fprintf(ac.output(), "✓ Created %s in %s\n", ac.branchName, strings.Join(names, ", "))
+	return nil
+}
+
+// forRepo returns a copy of ac for adding the worktree to another repository.
+// The exec commands are copied too, as running them sets their directory.
+func (ac *AddCommand) forRepo() *AddCommand {
+	repoAdd := *ac
+	repoAdd.repos = nil
+	repoAdd.execCommands = nil
+	for _, cmd := range ac.execCommands {
+		repoCmd := *cmd
+		repoCmd.Env = slices.Clone(cmd.Env)
+		repoAdd.execCommands = append(repoAdd.execCommands, &repoCmd)
+	}
+	return &repoAdd
+}
+
+// prepare derives the branch of a pull request and validates the options
+func (ac *AddCommand) prepare(repoPath string) error {
+	if ac.prNumber > 0 {
+		ac.branchName = fmt.Sprintf("pr/%d", ac.prNumber)
+		ac.createBranch = true
+	}
+
 	// Validate required fields
 	if ac.branchName == "" {
 		return fmt.Errorf("branch name is required")
 	}
+	return ac.validateMode(repoPath)
+}
+
+// abort rolls back tx after err, unless keepOnFailure is set, in which case
+// what describes the partial result that is kept. copies are the commands made
+// by forRepo that recorded steps in tx.
+func (ac *AddCommand) abort(tx *transaction, err error, what string, copies ...*AddCommand) error {
+	if ac.keepOnFailure {
+		fmt.Fprintf(ac.output(), "Keeping partially created %s (--keep-on-failure)\n", what)
+		return err
+	}
+	// A cancelled context must not stop the rollback; the undo steps use the
+	// gitService of the command that recorded them
+	for _, c := range append(copies, ac) {
+		c.gitService = c.gitService.WithContext(context.Background())
+	}
+	if rbErr := tx.rollback(); rbErr != nil {
+		return fmt.Errorf("%w (rollback incomplete: %v)", err, rbErr)
+	}
+	return err
+}
+
+// execute performs the steps of the add command, recording each change in tx
+func (ac *AddCommand) execute(repoPath string, tx *transaction) error {
+	if ac.prNumber > 0 {
+		done, err := ac.fetchPullRequest(repoPath, tx)
+		if err != nil || done {
+			return err
+		}
+	}
 
-	// Determine the worktree path if not set
+	// A branch that only exists on a remote gets a local tracking branch
+	trackRemote := ""
+	if !ac.createBranch && !ac.orphan && !ac.detach {
+		remote, err := ac.resolveRemoteBranch(repoPath)
+		if err != nil {
+			return err
+		}
+		trackRemote = remote
+	}
+
+	// Determine the worktree path if not set. Branch names such as feature/login
+	// become flat directory names (feature-login) so that they cannot collide with
+	// bugfix/login or nest inside each other.
 	if ac.worktreePath == "" {
-		parentDir := filepath.Dir(repoPath)
-		repoName := filepath.Base(repoPath)
-		worktreesDir := filepath.Join(parentDir, "worktrees", repoName)
-		ac.worktreePath = filepath.Join(worktreesDir, ac.branchName)
+		worktreesDir := ac.gitService.GetWorktreesDir(repoPath)
+		name := utils.Slug(ac.branchName)
+		if ac.dirName != "" {
+			name = utils.Slug(ac.dirName)
+		} else if ac.detach {
+			// Commits such as HEAD~2 make poor directory names; use the short hash
+			commit, err := ac.gitService.ResolveRef(repoPath, ac.branchName)
+			if err != nil {
+				return err
+			}
+			name = "detached-" + commit[:7]
+		}
+		ac.worktreePath = filepath.Join(worktreesDir, name)
+	}
+	if err := ac.checkWorktreePath(repoPath); err != nil {
+		return err
 	}
 
+	cfg, err := ac.gitService.LoadConfig(repoPath)
+	if err != nil {
+		return err
+	}
+	sparseDirs, err := ac.resolveSparseDirs(cfg)
+	if err != nil {
+		return err
+	}
+	noCheckout := len(sparseDirs) > 0
+
 	// Create worktrees directory if it doesn't exist
 	if err := ac.gitService.EnsureWorktreesDir(repoPath); err != nil {
 		return fmt.Errorf("failed to create worktrees directory: %w", err)
 	}
 
+	// Take the uncommitted changes out of the current worktree. They stay in the
+	// stash until the add has succeeded, so they can always be restored.
+	stash := ""
+	if ac.carry {
+		stash, err = ac.gitService.StashPush(repoPath, "wt add --carry "+ac.branchName, ac.carryUntracked)
+		if err != nil {
+			return err
+		}
+		if stash == "" {
+			fmt.Fprintln(ac.output(), "No local changes to carry")
+		} else {
+			tx.record("restore uncommitted changes in "+repoPath, func() error {
+				return ac.restoreCarried(repoPath, stash)
+			})
+			// Branch from the commit the changes were made on
+			if ac.createBranch && ac.startPoint == "" {
+				ac.startPoint = "HEAD"
+			}
+		}
+	}
+
+	// Only branches and directories that do not exist yet are ours to remove
+	branchExisted := ac.gitService.BranchExists(repoPath, ac.branchName)
+	_, statErr := os.Stat(ac.worktreePath)
+	dirExisted := statErr == nil
+
 	// Create the worktree
-	var err error
-	if ac.createBranch {
+	if ac.orphan {
+		err = ac.gitService.AddOrphanWorktree(repoPath, ac.worktreePath, ac.branchName)
+	} else if ac.detach {
+		err = ac.gitService.AddDetachedWorktree(repoPath, ac.worktreePath, ac.branchName, noCheckout)
+	} else if ac.createBranch {
 		// Create worktree with new branch
 		if ac.startPoint != "" {
-			err = ac.gitService.AddWorktreeWithBranch(ac.worktreePath, ac.branchName, ac.startPoint)
+			err = ac.gitService.AddWorktreeWithBranch(repoPath, ac.worktreePath, ac.branchName, ac.startPoint, noCheckout)
 		} else {
 			// Try to use default branch if no start point provided
 			defaultRef, refErr := ac.gitService.GetDefaultRef(repoPath)
 			if refErr == nil {
-				err = ac.gitService.AddWorktreeWithBranch(ac.worktreePath, ac.branchName, defaultRef)
+				err = ac.gitService.AddWorktreeWithBranch(repoPath, ac.worktreePath, ac.branchName, defaultRef, noCheckout)
 			} else {
 				// Fall back to HEAD
-				err = ac.gitService.AddWorktreeWithBranch(ac.worktreePath, ac.branchName, "")
+				err = ac.gitService.AddWorktreeWithBranch(repoPath, ac.worktreePath, ac.branchName, "", noCheckout)
 			}
 		}
+	} else if trackRemote != "" {
+		// Create a local branch tracking the remote branch
+		fmt.Fprintf(ac.output(), "Tracking %s/%s\n", trackRemote, ac.branchName)
+		err = ac.gitService.AddWorktreeTracking(repoPath, ac.worktreePath, ac.branchName, trackRemote, noCheckout)
 	} else {
 		// Create worktree from existing ref
-		err = ac.gitService.AddWorktree(ac.worktreePath, ac.branchName)
+		err = ac.gitService.AddWorktree(repoPath, ac.worktreePath, ac.branchName, noCheckout)
+	}
+
+	// git may have created the branch even if adding the worktree failed
+	if !branchExisted && ac.gitService.BranchExists(repoPath, ac.branchName) {
+		tx.record("delete branch "+ac.branchName, func() error {
+			return ac.gitService.DeleteBranch(repoPath, ac.branchName)
+		})
+	}
+	if !dirExisted {
+		tx.record("remove worktree "+ac.worktreePath, func() error {
+			return ac.removeWorktree(repoPath)
+		})
 	}
 
 	if err != nil {
 		return fmt.Errorf("failed to create worktree: %w", err)
 	}
 
-	fmt.Printf("Worktree created at: %s\n", ac.worktreePath)
+	// Sparse worktrees are created empty and only checked out once the patterns are set
+	if noCheckout {
+		if err := ac.gitService.SetSparseDirs(ac.worktreePath, sparseDirs); err != nil {
+			return err
+		}
+		if err := ac.gitService.CheckoutHead(ac.worktreePath); err != nil {
+			return err
+		}
+		fmt.Fprintf(ac.output(), "Checked out %s\n", strings.Join(sparseDirs, ", "))
+	}
+
+	fmt.Fprintf(ac.output(), "Worktree created at: %s\n", ac.worktreePath)
+
+	if !ac.noSubmodules && ac.gitService.HasSubmodules(ac.worktreePath) {
+		if err := ac.updateSubmodules(repoPath); err != nil {
+			return err
+		}
+	}
+
+	if ac.lock {
+		if err := ac.gitService.LockWorktree(repoPath, ac.worktreePath, ac.lockReason); err != nil {
+			return err
+		}
+		// A locked worktree must be unlocked before it can be removed
+		tx.record("unlock worktree "+ac.worktreePath, func() error {
+			return ac.gitService.UnlockWorktree(repoPath, ac.worktreePath)
+		})
+		fmt.Fprintln(ac.output(), "Worktree locked")
+	}
+
+	if stash != "" {
+		if err := ac.gitService.StashApply(ac.worktreePath, stash); err != nil {
+			fmt.Fprintf(ac.output(), "Your changes are safe in stash %s (git stash apply --index %s)\n", stash, stash)
+			return fmt.Errorf("failed to carry changes into the new worktree: %w", err)
+		}
+		fmt.Fprintln(ac.output(), "Carried uncommitted changes into the new worktree")
+	}
+
+	// Bring over ignored local files such as .env from the main worktree
+	if err := ac.applyLocalFiles(repoPath, cfg); err != nil {
+		return err
+	}
 
 	// Configure upstream tracking branch when a new branch was created
-	if ac.createBranch {
-		if err := ac.gitService.SetUpstreamBranch(repoPath, ac.branchName); err != nil {
-			fmt.Printf("Warning: could not set upstream branch: %v\n", err)
+	if ac.createBranch && ac.prNumber == 0 {
+		if err := ac.setUpstream(repoPath); err != nil {
+			return err
 		}
 	}
 
@@ -119,6 +485,427 @@ func (ac *AddCommand) Execute(repoPath string) error {
 		}
 	}
 
+	if stash != "" {
+		if err := ac.gitService.StashDrop(repoPath, stash); err != nil {
+			fmt.Fprintf(ac.output(), "Warning: could not drop carried stash: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// validateMode checks that at most one way of creating the worktree was requested
+// and that new branch names are valid
+func (ac *AddCommand) validateMode(repoPath string) error {
+	modes := 0
+	for _, set := range []bool{ac.createBranch && ac.prNumber == 0, ac.orphan, ac.detach, ac.prNumber > 0} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return fmt.Errorf("-b, --orphan, --detach and --pr cannot be combined")
+	}
+	if ac.createBranch || ac.orphan {
+		if err := ac.gitService.CheckBranchName(repoPath, ac.branchName); err != nil {
+			return err
+		}
+	}
+	if ac.lockReason != "" && !ac.lock {
+		return fmt.Errorf("--reason requires --lock")
+	}
+	if ac.remote != "" && ac.prNumber == 0 && (ac.createBranch || ac.orphan || ac.detach) {
+		return fmt.Errorf("--remote cannot be used with -b, --orphan or --detach")
+	}
+	if ac.orphan && (len(ac.sparseDirs) > 0 || len(ac.sparseProfiles) > 0) {
+		return fmt.Errorf("--sparse cannot be used with --orphan")
+	}
+
+	return nil
+}
+
+// checkWorktreePath fails if the worktree directory is used by another worktree or
+// is not empty, suggesting a free directory name instead
+func (ac *AddCommand) checkWorktreePath(repoPath string) error {
+	worktrees, err := ac.gitService.GetWorktrees(repoPath)
+	if err != nil {
+		return err
+	}
+
+	problem := func(path string) string {
+		for _, wt := range worktrees {
+			if samePath(wt.Path, path) {
+				owner := wt.Branch
+				if owner == "" {
+					owner = wt.CommitHash
+				}
+				return fmt.Sprintf("is already used by the worktree of %s", owner)
+			}
+		}
+		if entries, err := os.ReadDir(path); err == nil && len(entries) > 0 {
+			return "exists and is not empty"
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return "exists and is not a directory"
+		}
+		return ""
+	}
+
+	reason := problem(ac.worktreePath)
+	if reason == "" {
+		return nil
+	}
+	base := filepath.Base(ac.worktreePath)
+	for i := 2; ; i++ {
+		alternative := fmt.Sprintf("%s-%d", base, i)
+		if problem(filepath.Join(filepath.Dir(ac.worktreePath), alternative)) == "" {
+			return fmt.Errorf("worktree directory %s %s; choose another with --name %s", ac.worktreePath, reason, alternative)
+		}
+	}
+}
+
+// samePath reports whether two paths refer to the same location, resolving symlinks
+// where possible
+func samePath(a, b string) bool {
+	if resolved, err := filepath.EvalSymlinks(a); err == nil {
+		a = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(b); err == nil {
+		b = resolved
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// resolveSparseDirs combines the --sparse directories with those of the named profiles
+func (ac *AddCommand) resolveSparseDirs(cfg *models.Config) ([]string, error) {
+	profileDirs, err := sparseProfileDirs(cfg, ac.sparseProfiles)
+	if err != nil {
+		return nil, err
+	}
+	dirs := append(append([]string{}, ac.sparseDirs...), profileDirs...)
+	if len(dirs) > 0 {
+		if err := ac.gitService.RequireCapability(models.CapSparseCheckout); err != nil {
+			return nil, err
+		}
+	}
+	return dirs, nil
+}
+
+// updateSubmodules initializes the submodules of the new worktree, borrowing
+// objects from submodules the repository has already cloned
+func (ac *AddCommand) updateSubmodules(repoPath string) error {
+	submodules, err := ac.gitService.GetSubmodules(ac.worktreePath)
+	if err != nil {
+		return err
+	}
+	references := map[string]string{}
+	for name, path := range submodules {
+		if store := ac.gitService.FindSubmoduleStore(repoPath, name, path); store != "" {
+			references[path] = store
+		}
+	}
+
+	fmt.Fprintf(ac.output(), "Updating %d submodule(s)...\n", len(submodules))
+	if err := ac.gitService.UpdateSubmodules(ac.worktreePath, references); err != nil {
+		return fmt.Errorf("%w (use --no-submodules to skip them)", err)
+	}
+	return nil
+}
+
+// fetchPullRequest fetches the pull request head and makes it the start point of
+// the new branch. If the pull request already has a worktree and refresh is set,
+// that worktree is updated instead and done is true.
+func (ac *AddCommand) fetchPullRequest(repoPath string, tx *transaction) (done bool, err error) {
+	remote := ac.remote
+	if remote == "" {
+		remote = ac.gitService.GetBaseRemote(repoPath)
+	}
+	ref := services.PullRequestRef(ac.prNumber)
+	previous, _ := ac.gitService.ResolveRef(repoPath, ref)
+
+	existing, err := ac.findWorktreeForBranch(repoPath)
+	if err != nil {
+		return false, err
+	}
+	if ac.gitService.BranchExists(repoPath, ac.branchName) && !ac.refresh {
+		return false, fmt.Errorf("branch %s already exists; use --refresh to update it", ac.branchName)
+	}
+	if existing == "" && ac.gitService.BranchExists(repoPath, ac.branchName) {
+		return false, fmt.Errorf("branch %s exists but has no worktree; run 'wt add %s' to check it out", ac.branchName, ac.branchName)
+	}
+
+	fmt.Fprintf(ac.output(), "Fetching pull request #%d from %s\n", ac.prNumber, remote)
+	head, err := ac.gitService.FetchPullRequest(repoPath, remote, ac.prNumber)
+	if err != nil {
+		return false, err
+	}
+	if existing == "" {
+		if previous == "" {
+			tx.record("delete "+ref, func() error {
+				return ac.gitService.DeleteRef(repoPath, ref)
+			})
+		}
+		ac.startPoint = ref
+		return false, nil
+	}
+
+	ac.worktreePath = existing
+	if err := ac.refreshPullRequest(repoPath, previous, head); err != nil {
+		// Put the ref back, as the next --refresh compares the worktree with it
+		// to tell a force-push from local commits
+		if restoreErr := ac.restoreRef(repoPath, ref, previous); restoreErr != nil {
+			return true, fmt.Errorf("%w (%v)", err, restoreErr)
+		}
+		return true, err
+	}
+	return true, nil
+}
+
+// restoreRef points ref back at previous, or deletes it if previous is empty
+func (ac *AddCommand) restoreRef(repoPath, ref, previous string) error {
+	if previous == "" {
+		return ac.gitService.DeleteRef(repoPath, ref)
+	}
+	return ac.gitService.UpdateRef(repoPath, ref, previous)
+}
+
+// refreshPullRequest updates the worktree of a pull request to its newly fetched head.
+// Pull requests are often force-pushed, so the branch is reset when it has no
+// commits of its own; otherwise only a fast-forward is allowed.
+func (ac *AddCommand) refreshPullRequest(repoPath, previous, head string) error {
+	clean, err := ac.gitService.IsWorktreeClean(ac.worktreePath)
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return fmt.Errorf("worktree %s has uncommitted changes; commit or stash them before refreshing", ac.worktreePath)
+	}
+
+	current, err := ac.gitService.ResolveRef(ac.worktreePath, "HEAD")
+	if err != nil {
+		return err
+	}
+	switch {
+	case current == head:
+		fmt.Fprintf(ac.output(), "%s is already up to date\n", ac.branchName)
+		return nil
+	case current == previous:
+		err = ac.gitService.ResetHard(ac.worktreePath, head)
+	case ac.gitService.IsAncestor(ac.worktreePath, current, head):
+		err = ac.gitService.FastForward(ac.worktreePath, head)
+	default:
+		return fmt.Errorf("%s has local commits that are not in pull request #%d; update it by hand", ac.branchName, ac.prNumber)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ac.output(), "Refreshed %s at %s to %.7s\n", ac.branchName, ac.worktreePath, head)
+	return nil
+}
+
+// findWorktreeForBranch returns the path of the worktree that has the branch
+// checked out, or "" if there is none
+func (ac *AddCommand) findWorktreeForBranch(repoPath string) (string, error) {
+	worktrees, err := ac.gitService.GetWorktrees(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == ac.branchName {
+			return wt.Path, nil
+		}
+	}
+	return "", nil
+}
+
+// setUpstream configures a new branch to track its counterpart on the push remote.
+// In a fork workflow, where the push remote differs from the base remote the
+// branch was started from, 'git push' is also pointed at the push remote.
+func (ac *AddCommand) setUpstream(repoPath string) error {
+	if err := ac.gitService.SetUpstreamBranch(repoPath, ac.branchName); err != nil {
+		return fmt.Errorf("failed to set upstream branch: %w", err)
+	}
+
+	pushRemote := ac.gitService.GetPushRemote(repoPath)
+	if pushRemote == ac.gitService.GetBaseRemote(repoPath) {
+		return nil
+	}
+	remotes, err := ac.gitService.GetRemotes(repoPath)
+	if err != nil || !slices.Contains(remotes, pushRemote) {
+		return nil
+	}
+	if err := ac.gitService.SetBranchPushRemote(repoPath, ac.branchName, pushRemote); err != nil {
+		return err
+	}
+	fmt.Fprintf(ac.output(), "Pushes of %s go to %s\n", ac.branchName, pushRemote)
+	return nil
+}
+
+// restoreCarried puts carried changes back into the worktree they came from
+func (ac *AddCommand) restoreCarried(sourcePath, stash string) error {
+	if err := ac.gitService.StashApply(sourcePath, stash); err != nil {
+		return fmt.Errorf("%w; your changes are kept in stash %s", err, stash)
+	}
+	return ac.gitService.StashDrop(sourcePath, stash)
+}
+
+// resolveRemoteBranch returns the remote to track when the branch to check out
+// does not exist locally but exists as a remote-tracking branch. Both "feature-x"
+// and "origin/feature-x" are accepted; in the latter case the branch name is
+// shortened to "feature-x". It returns "" when no tracking branch is needed.
+func (ac *AddCommand) resolveRemoteBranch(repoPath string) (string, error) {
+	if ac.gitService.BranchExists(repoPath, ac.branchName) {
+		if ac.remote != "" {
+			return "", fmt.Errorf("branch %q already exists locally, so --remote %s has no effect; leave it out or use 'git branch --set-upstream-to'", ac.branchName, ac.remote)
+		}
+		return "", nil
+	}
+
+	remotes, err := ac.gitService.GetRemotes(repoPath)
+	if err != nil {
+		return "", err
+	}
+	for _, remote := range remotes {
+		name, ok := strings.CutPrefix(ac.branchName, remote+"/")
+		if !ok || ac.gitService.BranchExists(repoPath, name) {
+			continue
+		}
+		if found, _ := ac.gitService.FindRemoteBranches(repoPath, name); slices.Contains(found, remote) {
+			ac.branchName = name
+			return remote, nil
+		}
+	}
+
+	found, err := ac.gitService.FindRemoteBranches(repoPath, ac.branchName)
+	if err != nil {
+		return "", err
+	}
+	if ac.remote != "" {
+		if !slices.Contains(found, ac.remote) {
+			return "", fmt.Errorf("branch %q does not exist on remote %q", ac.branchName, ac.remote)
+		}
+		return ac.remote, nil
+	}
+
+	switch len(found) {
+	case 0:
+		// Not a remote branch; let git resolve it as a commit or tag
+		return "", nil
+	case 1:
+		return found[0], nil
+	default:
+		if ac.input == nil {
+			return "", fmt.Errorf("branch %q exists on remotes %s; choose one with --remote",
+				ac.branchName, strings.Join(found, ", "))
+		}
+		remote, err := promptChoice(ac.output(), ac.input, fmt.Sprintf("Branch %q exists on several remotes:", ac.branchName), found)
+		if err != nil {
+			return "", fmt.Errorf("branch %q exists on remotes %s; choose one with --remote: %w",
+				ac.branchName, strings.Join(found, ", "), err)
+		}
+		return remote, nil
+	}
+}
+
+// applyLocalFiles copies (wt.copy) and symlinks (wt.link) files matching the configured
+// globs from the main worktree into the new one. Files that already exist in the
+// new worktree are never overwritten, and globs matching nothing only produce a warning.
+func (ac *AddCommand) applyLocalFiles(repoPath string, cfg *models.Config) error {
+	if len(cfg.Copy) == 0 && len(cfg.Link) == 0 {
+		return nil
+	}
+
+	mainPath, err := ac.gitService.GetMainWorktree(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to find main worktree: %w", err)
+	}
+	if mainPath == ac.worktreePath {
+		return nil
+	}
+	if ac.gitService.IsBareRepository(repoPath) {
+		fmt.Fprintln(ac.output(), "Warning: wt.copy and wt.link are ignored in a bare repository, which has no main worktree to copy from")
+		return nil
+	}
+
+	for _, pattern := range cfg.Copy {
+		matches, err := matchLocalFiles(ac.output(), mainPath, "wt.copy", pattern)
+		if err != nil {
+			return err
+		}
+		for _, rel := range matches {
+			result, err := utils.CopyNoClobber(filepath.Join(mainPath, rel), filepath.Join(ac.worktreePath, rel))
+			if err != nil {
+				return fmt.Errorf("failed to copy %s: %w", rel, err)
+			}
+			for _, path := range result.Skipped {
+				fmt.Fprintf(ac.output(), "Warning: not overwriting existing %s\n", path)
+			}
+			if result.Copied > 0 {
+				fmt.Fprintf(ac.output(), "Copied %s\n", rel)
+			}
+		}
+	}
+
+	for _, pattern := range cfg.Link {
+		matches, err := matchLocalFiles(ac.output(), mainPath, "wt.link", pattern)
+		if err != nil {
+			return err
+		}
+		for _, rel := range matches {
+			linked, err := utils.SymlinkNoClobber(filepath.Join(mainPath, rel), filepath.Join(ac.worktreePath, rel))
+			if err != nil {
+				return err
+			}
+			if !linked {
+				fmt.Fprintf(ac.output(), "Warning: not overwriting existing %s\n", filepath.Join(ac.worktreePath, rel))
+				continue
+			}
+			fmt.Fprintf(ac.output(), "Linked %s\n", rel)
+		}
+	}
+
+	return nil
+}
+
+// matchLocalFiles expands a wt.copy or wt.link glob in the main worktree and
+// returns the matches relative to it
+func matchLocalFiles(out io.Writer, mainPath, key, pattern string) ([]string, error) {
+	if filepath.IsAbs(pattern) || slices.Contains(strings.Split(filepath.ToSlash(pattern), "/"), "..") {
+		return nil, fmt.Errorf("invalid %s pattern %q: must be relative to the main worktree", key, pattern)
+	}
+	matches, err := filepath.Glob(filepath.Join(mainPath, pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s pattern %q: %w", key, pattern, err)
+	}
+
+	var result []string
+	for _, match := range matches {
+		rel, err := filepath.Rel(mainPath, match)
+		if err != nil || rel == ".git" || strings.HasPrefix(rel, ".git"+string(filepath.Separator)) {
+			continue
+		}
+		result = append(result, rel)
+	}
+	if len(result) == 0 {
+		fmt.Fprintf(out, "Warning: %s %q matched nothing in %s\n", key, pattern, mainPath)
+	}
+	return result, nil
+}
+
+// removeWorktree removes the worktree created by this command, including any
+// files left behind when git did not get as far as registering it
+func (ac *AddCommand) removeWorktree(repoPath string) error {
+	if err := ac.gitService.RemoveWorktree(repoPath, ac.worktreePath, true); err != nil {
+		if _, statErr := os.Stat(ac.worktreePath); statErr != nil {
+			// Nothing was created on disk
+			return nil
+		}
+		if rmErr := os.RemoveAll(ac.worktreePath); rmErr != nil {
+			return rmErr
+		}
+		return ac.gitService.PruneWorktrees(repoPath, "")
+	}
 	return nil
 }
 
@@ -128,13 +915,16 @@ func (ac *AddCommand) executeCommands() error {
 		return nil
 	}
 
-	fmt.Printf("Executing %d command(s) in worktree...\n", len(ac.execCommands))
+	fmt.Fprintf(ac.output(), "Executing %d command(s) in worktree...\n", len(ac.execCommands))
 
 	// Set working directory for all commands
 	for _, cmd := range ac.execCommands {
 		cmd.Dir = ac.worktreePath
 
-		// Add worktree-specific environment variables
+		// Add worktree-specific environment variables on top of the inherited ones
+		if len(cmd.Env) == 0 {
+			cmd.Env = os.Environ()
+		}
 		cmd.Env = append(cmd.Env,
 			fmt.Sprintf("WT_WORKTREE=%s", ac.worktreePath),
 			fmt.Sprintf("WT_BRANCH=%s", ac.branchName),
@@ -152,9 +942,9 @@ func (ac *AddCommand) executeCommands() error {
 	for i, result := range results {
 		cmdDesc := ac.execCommands[i].Name + " " + strings.Join(ac.execCommands[i].Args, " ")
 		if result.Success {
-			fmt.Printf("✓ %s completed successfully\n", cmdDesc)
+			fmt.Fprintf(ac.output(), "✓ %s completed successfully\n", cmdDesc)
 		} else {
-			fmt.Printf("✗ %s failed: %s\n", cmdDesc, result.Error)
+			fmt.Fprintf(ac.output(), "✗ %s failed: %s\n", cmdDesc, result.Error)
 			allSuccess = false
 		}
 	}
@@ -167,16 +957,27 @@ func (ac *AddCommand) executeCommands() error {
 }
 
 // RunAddCommand is the entry point for the add command
-func RunAddCommand(repoPath, gitPath string, createBranch, verbose bool, branchName, startPoint string, execCommands []*utils.Command) error {
+func RunAddCommand(repoPath, gitPath string, opts AddOptions) error {
 	gitService := services.NewGitService(gitPath)
 	addCmd := NewAddCommand(gitService)
-	addCmd.SetCreateBranch(createBranch)
-	addCmd.SetBranchName(branchName)
-	addCmd.SetStartPoint(startPoint)
-	addCmd.SetVerbose(verbose)
+	addCmd.SetCreateBranch(opts.CreateBranch)
+	addCmd.SetBranchName(opts.BranchName)
+	addCmd.SetStartPoint(opts.StartPoint)
+	addCmd.SetVerbose(opts.Verbose)
+	addCmd.SetKeepOnFailure(opts.KeepOnFailure)
+	addCmd.SetCarry(opts.Carry, opts.Untracked)
+	addCmd.SetRemote(opts.Remote)
+	addCmd.SetPullRequest(opts.PRNumber, opts.Refresh)
+	addCmd.SetOrphan(opts.Orphan)
+	addCmd.SetDetach(opts.Detach)
+	addCmd.SetLock(opts.Lock, opts.LockReason)
+	addCmd.SetSparse(opts.Sparse, opts.SparseProfile)
+	addCmd.SetNoSubmodules(opts.NoSubmodules)
+	addCmd.SetDirName(opts.Name)
+	addCmd.SetRepos(opts.Repos)
 
 	// Add exec commands
-	for _, cmd := range execCommands {
+	for _, cmd := range opts.ExecCommands {
 		addCmd.AddExecCommand(cmd)
 	}
 
@@ -0,0 +1,612 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+	"github.com/smoerfugl/wt/internal/utils"
+)
+
+func TestAddRollsBackOnFailedExec(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("feature-fail")
+	ac.AddExecCommand(utils.NewCommand("sh", []string{"-c", "exit 3"}))
+
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil {
+		t.Fatalf("expected add to fail")
+	}
+
+	if gs.BranchExists(repoDir, "feature-fail") {
+		t.Errorf("branch created by wt was not deleted")
+	}
+	wtPath := filepath.Join(gs.GetWorktreesDir(repoDir), "feature-fail")
+	if _, err := os.Stat(wtPath); err == nil {
+		t.Errorf("worktree directory %s was not removed", wtPath)
+	}
+	if list := runTestGit(t, repoDir, "worktree", "list"); strings.Contains(list, "feature-fail") {
+		t.Errorf("worktree still registered:\n%s", list)
+	}
+
+	// A retry must not fail because the branch already exists
+	ac = NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("feature-fail")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+}
+
+func TestAddKeepsExistingBranchOnFailure(t *testing.T) {
+	repoDir := newTestRepo(t)
+	runTestGit(t, repoDir, "branch", "existing")
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetBranchName("existing")
+	ac.AddExecCommand(utils.NewCommand("sh", []string{"-c", "exit 1"}))
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil {
+		t.Fatalf("expected add to fail")
+	}
+	if !gs.BranchExists(repoDir, "existing") {
+		t.Errorf("pre-existing branch was deleted during rollback")
+	}
+}
+
+func TestAddKeepOnFailure(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("kept")
+	ac.SetKeepOnFailure(true)
+	ac.AddExecCommand(utils.NewCommand("sh", []string{"-c", "exit 1"}))
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil {
+		t.Fatalf("expected add to fail")
+	}
+	if !gs.BranchExists(repoDir, "kept") {
+		t.Errorf("branch was removed despite --keep-on-failure")
+	}
+}
+
+func TestAddCopiesAndLinksLocalFiles(t *testing.T) {
+	repoDir := newTestRepo(t)
+	if err := os.WriteFile(filepath.Join(repoDir, ".gitignore"), []byte(".env*\ncache/\n"), 0644); err != nil {
+		t.Fatalf("write .gitignore: %v", err)
+	}
+	runTestGit(t, repoDir, "add", ".gitignore")
+	runTestGit(t, repoDir, "commit", "-m", "ignore")
+	os.WriteFile(filepath.Join(repoDir, ".env"), []byte("SECRET=1"), 0600)
+	os.WriteFile(filepath.Join(repoDir, ".env.local"), []byte("LOCAL=1"), 0600)
+	os.Mkdir(filepath.Join(repoDir, "cache"), 0755)
+	runTestGit(t, repoDir, "config", "--add", "wt.copy", ".env*")
+	runTestGit(t, repoDir, "config", "--add", "wt.copy", "missing.txt")
+	runTestGit(t, repoDir, "config", "--add", "wt.copy", "README.md") // Checked out already
+	runTestGit(t, repoDir, "config", "--add", "wt.link", "cache")
+
+	gs := services.NewGitService("")
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("local-files")
+	out, err := captureStdout(t, func() error { return ac.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("add failed: %v\n%s", err, out)
+	}
+
+	wtPath := filepath.Join(gs.GetWorktreesDir(repoDir), "local-files")
+	if got, _ := os.ReadFile(filepath.Join(wtPath, ".env")); string(got) != "SECRET=1" {
+		t.Errorf(".env not copied: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, ".env.local")); err != nil {
+		t.Errorf(".env.local not copied: %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(wtPath, "cache")); err != nil || target != filepath.Join(repoDir, "cache") {
+		t.Errorf("cache link = %q, %v", target, err)
+	}
+	if !strings.Contains(out, `Warning: wt.copy "missing.txt" matched nothing`) {
+		t.Errorf("missing source did not produce a warning:\n%s", out)
+	}
+	if !strings.Contains(out, "not overwriting existing "+filepath.Join(wtPath, "README.md")) || strings.Contains(out, "Copied README.md") {
+		t.Errorf("skipped README.md reported as copied:\n%s", out)
+	}
+}
+
+func TestAddCarryMovesChanges(t *testing.T) {
+	repoDir := newTestRepo(t)
+	os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("staged"), 0644)
+	runTestGit(t, repoDir, "add", "README.md")
+	os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("unstaged"), 0644)
+	os.WriteFile(filepath.Join(repoDir, "notes.txt"), []byte("untracked"), 0644)
+
+	gs := services.NewGitService("")
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("carried")
+	ac.SetCarry(true, true)
+	if out, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add --carry failed: %v\n%s", err, out)
+	}
+
+	if clean, _ := gs.IsWorktreeClean(repoDir); !clean {
+		t.Errorf("source worktree is not clean after carry")
+	}
+	wtPath := filepath.Join(gs.GetWorktreesDir(repoDir), "carried")
+	if got, _ := os.ReadFile(filepath.Join(wtPath, "README.md")); string(got) != "unstaged" {
+		t.Errorf("unstaged change not carried: %q", got)
+	}
+	if got := runTestGit(t, wtPath, "show", ":README.md"); got != "staged" {
+		t.Errorf("staged change not carried: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "notes.txt")); err != nil {
+		t.Errorf("untracked file not carried: %v", err)
+	}
+	if stashes := runTestGit(t, repoDir, "stash", "list"); stashes != "" {
+		t.Errorf("carry left a stash entry behind: %s", stashes)
+	}
+}
+
+func TestAddCarryConflictRestoresSource(t *testing.T) {
+	repoDir := newTestRepo(t)
+	runTestGit(t, repoDir, "checkout", "-q", "-b", "other")
+	os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("other"), 0644)
+	runTestGit(t, repoDir, "commit", "-qam", "other")
+	runTestGit(t, repoDir, "checkout", "-q", "-")
+	os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("my work"), 0644)
+
+	gs := services.NewGitService("")
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("conflicting")
+	ac.SetStartPoint("other")
+	ac.SetCarry(true, false)
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil {
+		t.Fatalf("expected carry into a conflicting start point to fail")
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(repoDir, "README.md")); string(got) != "my work" {
+		t.Errorf("uncommitted work was not restored: %q", got)
+	}
+	if gs.BranchExists(repoDir, "conflicting") {
+		t.Errorf("branch was not rolled back")
+	}
+}
+
+func TestAddTracksRemoteOnlyBranch(t *testing.T) {
+	repoDir := newTestRepo(t)
+	addTestRemote(t, repoDir, "origin")
+	addTestRemote(t, repoDir, "upstream")
+	runTestGit(t, repoDir, "push", "-q", "origin", "HEAD:refs/heads/only-origin")
+	runTestGit(t, repoDir, "push", "-q", "origin", "HEAD:refs/heads/both")
+	runTestGit(t, repoDir, "push", "-q", "upstream", "HEAD:refs/heads/both")
+	runTestGit(t, repoDir, "fetch", "-q", "--all")
+	gs := services.NewGitService("")
+
+	upstreamOf := func(branch string) string {
+		return runTestGit(t, repoDir, "for-each-ref", "--format=%(upstream:short)", "refs/heads/"+branch)
+	}
+
+	ac := NewAddCommand(gs)
+	ac.SetBranchName("only-origin")
+	if out, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add only-origin failed: %v\n%s", err, out)
+	}
+	if got := upstreamOf("only-origin"); got != "origin/only-origin" {
+		t.Errorf("only-origin upstream = %q, want origin/only-origin", got)
+	}
+
+	// Several remotes have the branch: the prompt decides
+	ac = NewAddCommand(gs)
+	ac.SetBranchName("both")
+	ac.input = strings.NewReader("2\n")
+	if out, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add both failed: %v\n%s", err, out)
+	}
+	if got := upstreamOf("both"); got != "upstream/both" {
+		t.Errorf("both upstream = %q, want upstream/both", got)
+	}
+}
+
+func TestAddRemoteFlag(t *testing.T) {
+	repoDir := newTestRepo(t)
+	addTestRemote(t, repoDir, "origin")
+	addTestRemote(t, repoDir, "upstream")
+	runTestGit(t, repoDir, "push", "-q", "origin", "HEAD:refs/heads/shared")
+	runTestGit(t, repoDir, "push", "-q", "upstream", "HEAD:refs/heads/shared")
+	runTestGit(t, repoDir, "fetch", "-q", "--all")
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetBranchName("shared")
+	ac.SetRemote("missing")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil {
+		t.Fatalf("expected error for a remote without the branch")
+	}
+
+	ac = NewAddCommand(gs)
+	ac.SetBranchName("upstream/shared")
+	if out, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add upstream/shared failed: %v\n%s", err, out)
+	}
+	got := runTestGit(t, repoDir, "for-each-ref", "--format=%(upstream:short)", "refs/heads/shared")
+	if got != "upstream/shared" {
+		t.Errorf("shared upstream = %q, want upstream/shared", got)
+	}
+
+	// Once the branch exists locally --remote would be ignored, so it is refused
+	ac = NewAddCommand(gs)
+	ac.SetBranchName("shared")
+	ac.SetRemote("origin")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil || !strings.Contains(err.Error(), "already exists locally") {
+		t.Errorf("expected --remote with a local branch to fail, got %v", err)
+	}
+
+	ac = NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("new-feature")
+	ac.SetRemote("origin")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil || !strings.Contains(err.Error(), "--remote cannot be used") {
+		t.Errorf("expected --remote with -b to fail, got %v", err)
+	}
+}
+
+func TestAddPromptsForRemoteOnOutput(t *testing.T) {
+	repoDir := newTestRepo(t)
+	addTestRemote(t, repoDir, "origin")
+	addTestRemote(t, repoDir, "upstream")
+	runTestGit(t, repoDir, "push", "-q", "origin", "HEAD:refs/heads/shared")
+	runTestGit(t, repoDir, "push", "-q", "upstream", "HEAD:refs/heads/shared")
+	runTestGit(t, repoDir, "fetch", "-q", "--all")
+	gs := services.NewGitService("")
+
+	var buf bytes.Buffer
+	ac := NewAddCommand(gs)
+	ac.SetBranchName("shared")
+	ac.SetOutput(&buf)
+	ac.SetInput(strings.NewReader("2\n"))
+	stdout, err := captureStdout(t, func() error { return ac.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("add failed: %v\n%s", err, buf.String())
+	}
+	if !strings.Contains(buf.String(), "exists on several remotes") || strings.Contains(stdout, "several remotes") {
+		t.Errorf("prompt was not written to the command output:\nstdout:\n%s\noutput:\n%s", stdout, buf.String())
+	}
+	got := runTestGit(t, repoDir, "for-each-ref", "--format=%(upstream:short)", "refs/heads/shared")
+	if got != "upstream/shared" {
+		t.Errorf("shared upstream = %q, want upstream/shared", got)
+	}
+}
+
+func TestAddForkWorkflow(t *testing.T) {
+	repoDir := newTestRepo(t)
+	addTestRemote(t, repoDir, "origin")
+	addTestRemote(t, repoDir, "upstream")
+
+	// upstream has moved ahead of the local checkout
+	branch := runTestGit(t, repoDir, "symbolic-ref", "--short", "HEAD")
+	runTestGit(t, repoDir, "commit", "-q", "--allow-empty", "-m", "upstream work")
+	runTestGit(t, repoDir, "push", "-q", "upstream", "HEAD")
+	upstreamHead := runTestGit(t, repoDir, "rev-parse", "HEAD")
+	runTestGit(t, repoDir, "reset", "-q", "--hard", "HEAD~1")
+	runTestGit(t, repoDir, "fetch", "-q", "upstream")
+	runTestGit(t, repoDir, "remote", "set-head", "upstream", branch)
+	runTestGit(t, repoDir, "config", "wt.baseRemote", "upstream")
+	runTestGit(t, repoDir, "config", "wt.pushRemote", "origin")
+
+	gs := services.NewGitService("")
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("fork-feature")
+	if out, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add failed: %v\n%s", err, out)
+	}
+
+	if got := runTestGit(t, repoDir, "rev-parse", "fork-feature"); got != upstreamHead {
+		t.Errorf("fork-feature starts at %s, want upstream head %s", got, upstreamHead)
+	}
+	if got := runTestGit(t, repoDir, "config", "branch.fork-feature.pushRemote"); got != "origin" {
+		t.Errorf("pushRemote = %q, want origin", got)
+	}
+}
+
+func TestAddPullRequest(t *testing.T) {
+	repoDir := newTestRepo(t)
+	remoteDir := addTestRemote(t, repoDir, "origin")
+
+	// Publish a GitHub-style pull request and a GitLab-style merge request
+	runTestGit(t, repoDir, "commit", "-q", "--allow-empty", "-m", "pr 1")
+	runTestGit(t, repoDir, "push", "-q", "origin", "HEAD:refs/pull/1/head")
+	prHead := runTestGit(t, repoDir, "rev-parse", "HEAD")
+	runTestGit(t, repoDir, "commit", "-q", "--allow-empty", "-m", "mr 2")
+	runTestGit(t, repoDir, "push", "-q", "origin", "HEAD:refs/merge-requests/2/head")
+	runTestGit(t, repoDir, "reset", "-q", "--hard", "HEAD~2")
+
+	gs := services.NewGitService("")
+	ac := NewAddCommand(gs)
+	ac.SetPullRequest(1, false)
+	if out, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add --pr 1 failed: %v\n%s", err, out)
+	}
+	if got := runTestGit(t, repoDir, "rev-parse", "pr/1"); got != prHead {
+		t.Errorf("pr/1 = %s, want %s", got, prHead)
+	}
+
+	ac = NewAddCommand(gs)
+	ac.SetPullRequest(2, false)
+	if out, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add --pr 2 failed: %v\n%s", err, out)
+	}
+	if !gs.BranchExists(repoDir, "pr/2") {
+		t.Errorf("merge request branch pr/2 not created")
+	}
+
+	// Adding the same pull request again requires --refresh
+	ac = NewAddCommand(gs)
+	ac.SetPullRequest(1, false)
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil {
+		t.Errorf("expected error when the pull request branch already exists")
+	}
+
+	// The pull request is force-pushed; --refresh follows it
+	wtPath := filepath.Join(gs.GetWorktreesDir(repoDir), "pr-1")
+	runTestGit(t, wtPath, "commit", "-q", "--amend", "--allow-empty", "-m", "pr 1 rewritten")
+	rewritten := runTestGit(t, wtPath, "rev-parse", "HEAD")
+	runTestGit(t, wtPath, "push", "-q", "-f", remoteDir, "HEAD:refs/pull/1/head")
+	runTestGit(t, wtPath, "reset", "-q", "--hard", prHead)
+
+	ac = NewAddCommand(gs)
+	ac.SetPullRequest(1, true)
+	if out, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add --pr 1 --refresh failed: %v\n%s", err, out)
+	}
+	if got := runTestGit(t, wtPath, "rev-parse", "HEAD"); got != rewritten {
+		t.Errorf("refreshed pr/1 = %s, want %s", got, rewritten)
+	}
+
+	// A refused refresh leaves the fetched ref as it was, so the next one
+	// still recognizes the force-push
+	runTestGit(t, wtPath, "commit", "-q", "--amend", "--allow-empty", "-m", "pr 1 rewritten again")
+	again := runTestGit(t, wtPath, "rev-parse", "HEAD")
+	runTestGit(t, wtPath, "push", "-q", "-f", remoteDir, "HEAD:refs/pull/1/head")
+	runTestGit(t, wtPath, "reset", "-q", "--hard", rewritten)
+	if err := os.WriteFile(filepath.Join(wtPath, "dirty.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ac = NewAddCommand(gs)
+	ac.SetPullRequest(1, true)
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil {
+		t.Fatalf("expected refresh of a dirty worktree to fail")
+	}
+	if got := runTestGit(t, repoDir, "rev-parse", services.PullRequestRef(1)); got != rewritten {
+		t.Errorf("%s = %s after a refused refresh, want %s", services.PullRequestRef(1), got, rewritten)
+	}
+	if err := os.Remove(filepath.Join(wtPath, "dirty.txt")); err != nil {
+		t.Fatal(err)
+	}
+	ac = NewAddCommand(gs)
+	ac.SetPullRequest(1, true)
+	if out, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add --pr 1 --refresh after cleaning up failed: %v\n%s", err, out)
+	}
+	if got := runTestGit(t, wtPath, "rev-parse", "HEAD"); got != again {
+		t.Errorf("refreshed pr/1 = %s, want %s", got, again)
+	}
+
+	ac = NewAddCommand(gs)
+	ac.SetPullRequest(99, false)
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil {
+		t.Errorf("expected error for a missing pull request")
+	}
+}
+
+func TestAddDetached(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+	head := runTestGit(t, repoDir, "rev-parse", "HEAD")
+
+	ac := NewAddCommand(gs)
+	ac.SetBranchName("HEAD")
+	ac.SetDetach(true)
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add --detach failed: %v", err)
+	}
+
+	wtPath := filepath.Join(gs.GetWorktreesDir(repoDir), "detached-"+head[:7])
+	worktrees, err := gs.GetWorktrees(repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, wt := range worktrees {
+		if wt.Path == wtPath {
+			found = true
+			if !wt.IsDetached || !strings.HasPrefix(head, wt.CommitHash) {
+				t.Errorf("worktree not detached at %s: %+v", head, wt)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("worktree %s not registered", wtPath)
+	}
+}
+
+func TestAddLockedRollsBack(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("locked")
+	ac.SetLock(true, "on a usb drive")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add --lock failed: %v", err)
+	}
+	list := runTestGit(t, repoDir, "worktree", "list", "--porcelain")
+	if !strings.Contains(list, "locked on a usb drive") {
+		t.Errorf("worktree not locked with reason:\n%s", list)
+	}
+
+	// A failed exec must unlock the worktree so that it can be removed
+	ac = NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("locked-fail")
+	ac.SetLock(true, "")
+	ac.AddExecCommand(utils.NewCommand("sh", []string{"-c", "exit 1"}))
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil {
+		t.Fatalf("expected add to fail")
+	}
+	if list := runTestGit(t, repoDir, "worktree", "list"); strings.Contains(list, "locked-fail") {
+		t.Errorf("locked worktree was not rolled back:\n%s", list)
+	}
+}
+
+func TestAddModeValidation(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetDetach(true)
+	ac.SetBranchName("both")
+	if err := ac.Execute(repoDir); err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Errorf("expected -b with --detach to be rejected, got %v", err)
+	}
+
+	ac = NewAddCommand(gs)
+	ac.SetBranchName("reason")
+	ac.SetLock(false, "why")
+	if err := ac.Execute(repoDir); err == nil || !strings.Contains(err.Error(), "--reason requires --lock") {
+		t.Errorf("expected --reason without --lock to be rejected, got %v", err)
+	}
+}
+
+func TestAddOrphan(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	// Uses 'worktree add --orphan' on git >= 2.42 and the fallback on older git
+	ac := NewAddCommand(gs)
+	ac.SetBranchName("pages")
+	ac.SetOrphan(true)
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add --orphan failed: %v", err)
+	}
+
+	wtPath := filepath.Join(gs.GetWorktreesDir(repoDir), "pages")
+	if branch := runTestGit(t, wtPath, "symbolic-ref", "--short", "HEAD"); branch != "pages" {
+		t.Errorf("HEAD = %q, want pages", branch)
+	}
+	if files := runTestGit(t, wtPath, "ls-files"); files != "" {
+		t.Errorf("orphan worktree index not empty: %q", files)
+	}
+
+	worktrees, err := gs.GetWorktrees(repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, wt := range worktrees {
+		if wt.Path == wtPath && wt.GetMode() != models.ModeOrphan {
+			t.Errorf("mode = %q, want %q", wt.GetMode(), models.ModeOrphan)
+		}
+	}
+}
+
+func TestAddSlugsBranchNames(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	for _, branch := range []string{"feature/login", "bugfix/login"} {
+		ac := NewAddCommand(gs)
+		ac.SetCreateBranch(true)
+		ac.SetBranchName(branch)
+		if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+			t.Fatalf("add %s failed: %v", branch, err)
+		}
+	}
+
+	worktreesDir := gs.GetWorktreesDir(repoDir)
+	for _, dir := range []string{"feature-login", "bugfix-login"} {
+		if _, err := os.Stat(filepath.Join(worktreesDir, dir)); err != nil {
+			t.Errorf("expected worktree directory %s: %v", dir, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(worktreesDir, "feature")); err == nil {
+		t.Errorf("branch name should not create nested directories")
+	}
+}
+
+func TestAddDirectoryCollision(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("feature/login")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	// feature-login slugs to the same directory
+	ac = NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("feature-login")
+	_, err := captureStdout(t, func() error { return ac.Execute(repoDir) })
+	if err == nil || !strings.Contains(err.Error(), "--name feature-login-2") {
+		t.Fatalf("expected collision error suggesting feature-login-2, got %v", err)
+	}
+	if gs.BranchExists(repoDir, "feature-login") {
+		t.Errorf("branch was created despite the collision")
+	}
+
+	// A non-empty directory that is not a worktree
+	stray := filepath.Join(gs.GetWorktreesDir(repoDir), "stray")
+	if err := os.MkdirAll(stray, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stray, "notes.txt"), []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ac = NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("stray")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Fatalf("expected non-empty directory error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(stray, "notes.txt")); err != nil {
+		t.Errorf("existing file was removed: %v", err)
+	}
+
+	ac = NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("feature-login")
+	ac.SetDirName("feature-login-2")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add with --name failed: %v", err)
+	}
+}
+
+func TestAddRejectsInvalidBranchName(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	for _, name := range []string{"has space", "double..dot", "ends.lock", "-dash", "trailing/"} {
+		ac := NewAddCommand(gs)
+		ac.SetCreateBranch(true)
+		ac.SetBranchName(name)
+		if err := ac.Execute(repoDir); err == nil || !strings.Contains(err.Error(), "invalid branch name") {
+			t.Errorf("add -b %q: expected invalid branch name error, got %v", name, err)
+		}
+	}
+	entries, _ := os.ReadDir(gs.GetWorktreesDir(repoDir))
+	if len(entries) != 0 {
+		t.Errorf("worktrees were created for invalid branch names: %v", entries)
+	}
+}
@@ -0,0 +1,215 @@
+// Adopt command implementation
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+	"github.com/smoerfugl/wt/internal/utils"
+)
+
+// AdoptCommand handles the 'wt adopt' command
+type AdoptCommand struct {
+	gitService *services.GitService
+	worktrees  []string // Paths, directory names or branches of the worktrees to adopt
+	all        bool
+	dryRun     bool
+	yes        bool
+	input      io.Reader // Source of answers to the confirmation prompt
+}
+
+// adoption is a planned move of a worktree into the worktrees directory
+type adoption struct {
+	worktree models.Worktree
+	newPath  string
+	skip     string // Why the worktree is left alone, if it is
+}
+
+// NewAdoptCommand creates a new AdoptCommand instance
+func NewAdoptCommand(gitService *services.GitService) *AdoptCommand {
+	return &AdoptCommand{
+		gitService: gitService,
+		input:      os.Stdin,
+	}
+}
+
+// SetWorktrees sets the worktrees to adopt, by path, directory name or branch
+func (ac *AdoptCommand) SetWorktrees(worktrees []string) {
+	ac.worktrees = worktrees
+}
+
+// SetAll adopts every worktree outside the worktrees directory
+func (ac *AdoptCommand) SetAll(all bool) {
+	ac.all = all
+}
+
+// SetDryRun sets whether to only print the planned moves
+func (ac *AdoptCommand) SetDryRun(dryRun bool) {
+	ac.dryRun = dryRun
+}
+
+// SetYes moves the worktrees without asking first
+func (ac *AdoptCommand) SetYes(yes bool) {
+	ac.yes = yes
+}
+
+// SetInput sets where the answer to the confirmation prompt is read from;
+// end of input cancels the moves
+func (ac *AdoptCommand) SetInput(input io.Reader) {
+	ac.input = input
+}
+
+// Execute moves worktrees created outside the worktrees directory into it, naming
+// each directory like 'wt add' would. Locked and missing worktrees are left alone.
+func (ac *AdoptCommand) Execute(repoPath string) error {
+	if ac.all == (len(ac.worktrees) > 0) {
+		return fmt.Errorf("specify the worktrees to adopt or --all, but not both")
+	}
+
+	// Work from the main worktree: a worktree being moved may be the current directory
+	mainPath, err := ac.gitService.GetMainWorktree(repoPath)
+	if err != nil {
+		return err
+	}
+	worktrees, err := ac.gitService.GetWorktrees(mainPath)
+	if err != nil {
+		return fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	root := ac.gitService.GetWorktreesDir(mainPath)
+
+	var candidates []models.Worktree
+	if ac.all {
+		for _, wt := range worktrees {
+			if !samePath(wt.Path, mainPath) && !isWithin(wt.Path, root) {
+				candidates = append(candidates, wt)
+			}
+		}
+	} else {
+		for _, arg := range ac.worktrees {
+			wt, err := FindWorktree(worktrees, arg)
+			if err != nil {
+				return err
+			}
+			switch {
+			case samePath(wt.Path, mainPath):
+				return fmt.Errorf("the main worktree cannot be adopted")
+			case isWithin(wt.Path, root):
+				fmt.Printf("%s is already in %s\n", wt.Path, root)
+				continue
+			}
+			candidates = append(candidates, wt)
+		}
+	}
+	if len(candidates) == 0 {
+		fmt.Printf("Nothing to adopt: every worktree is in %s\n", root)
+		return nil
+	}
+
+	plan := planAdoptions(candidates, worktrees, root)
+	var moves []adoption
+	fmt.Printf("Planned moves into %s:\n", root)
+	for _, a := range plan {
+		if a.skip == "" {
+			moves = append(moves, a)
+			fmt.Printf("  %s -> %s\n", a.worktree.Path, a.newPath)
+		}
+	}
+	if len(moves) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, a := range plan {
+		if a.skip != "" {
+			fmt.Printf("Leaving %s alone: %s\n", a.worktree.Path, a.skip)
+		}
+	}
+
+	if ac.dryRun || len(moves) == 0 {
+		return nil
+	}
+	if !ac.yes && !promptYesNo(os.Stdout, ac.input, fmt.Sprintf("Move %d worktree(s)? [Y/n] ", len(moves)), false) {
+		fmt.Println("Cancelled")
+		return nil
+	}
+
+	if err := ac.gitService.EnsureWorktreesDir(mainPath); err != nil {
+		return err
+	}
+	failed := 0
+	cwd, _ := os.Getwd()
+	for _, a := range moves {
+		// git refuses to move some worktrees, such as those with submodules;
+		// report them and carry on with the rest
+		if err := ac.gitService.MoveWorktree(mainPath, a.worktree.Path, a.newPath); err != nil {
+			fmt.Printf("✗ %s: %v\n", a.worktree.Path, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✓ Moved %s to %s\n", a.worktree.Path, a.newPath)
+		if cwd != "" && isWithin(cwd, a.worktree.Path) {
+			fmt.Printf("Your shell is still in the old directory; run: cd %s\n", a.newPath)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d worktree(s) could not be moved", failed)
+	}
+	return nil
+}
+
+// planAdoptions works out where each candidate moves to. Directories are named
+// after the branch, or the old directory name for detached worktrees; a numeric
+// suffix is added when the name is taken by an existing directory or another move.
+func planAdoptions(candidates, worktrees []models.Worktree, root string) []adoption {
+	taken := map[string]bool{}
+	for _, wt := range worktrees {
+		taken[filepath.Clean(wt.Path)] = true
+	}
+
+	var plan []adoption
+	for _, wt := range candidates {
+		a := adoption{worktree: wt}
+		switch {
+		case wt.IsLocked:
+			a.skip = wt.DescribeLock()
+		case wt.IsPrunable():
+			a.skip = "missing (" + wt.PrunableReason + "); run 'wt prune'"
+		}
+		if a.skip != "" {
+			plan = append(plan, a)
+			continue
+		}
+
+		name := filepath.Base(wt.Path)
+		if wt.Branch != "" {
+			name = utils.Slug(wt.Branch)
+		}
+		a.newPath = filepath.Join(root, name)
+		for i := 2; taken[a.newPath] || pathExists(a.newPath); i++ {
+			a.newPath = filepath.Join(root, fmt.Sprintf("%s-%d", name, i))
+		}
+		taken[a.newPath] = true
+		plan = append(plan, a)
+	}
+	return plan
+}
+
+// pathExists reports whether anything exists at path
+func pathExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// RunAdoptCommand is the entry point for the adopt command
+func RunAdoptCommand(repoPath, gitPath string, worktrees []string, all, dryRun, yes bool) error {
+	gitService := services.NewGitService(gitPath)
+	adoptCmd := NewAdoptCommand(gitService)
+	adoptCmd.SetWorktrees(worktrees)
+	adoptCmd.SetAll(all)
+	adoptCmd.SetDryRun(dryRun)
+	adoptCmd.SetYes(yes)
+
+	return adoptCmd.Execute(repoPath)
+}
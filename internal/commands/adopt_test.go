@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+func TestAdoptAll(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+	elsewhere := t.TempDir()
+	handMade := filepath.Join(elsewhere, "scratch")
+	locked := filepath.Join(elsewhere, "locked")
+	detached := filepath.Join(elsewhere, "experiment")
+	runTestGit(t, repoDir, "worktree", "add", "-q", "-b", "feature/a", handMade)
+	runTestGit(t, repoDir, "worktree", "add", "-q", "-b", "hold", locked)
+	runTestGit(t, repoDir, "worktree", "lock", "--reason", "on a USB disk", locked)
+	runTestGit(t, repoDir, "worktree", "add", "-q", "--detach", detached)
+	inPlace := addTestWorktree(t, gs, repoDir, "done")
+
+	// A directory already takes the name feature/a would get
+	root := gs.GetWorktreesDir(repoDir)
+	if err := os.MkdirAll(filepath.Join(root, "feature-a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ac := NewAdoptCommand(gs)
+	ac.SetAll(true)
+	ac.SetDryRun(true)
+	output, err := captureStdout(t, func() error { return ac.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	wantMoves := map[string]string{
+		handMade: filepath.Join(root, "feature-a-2"),
+		detached: filepath.Join(root, "experiment"),
+	}
+	for from, to := range wantMoves {
+		if !strings.Contains(output, from+" -> "+to) {
+			t.Errorf("plan missing %s -> %s:\n%s", from, to, output)
+		}
+	}
+	if !strings.Contains(output, "on a USB disk") || strings.Contains(output, inPlace+" ->") {
+		t.Errorf("unexpected plan:\n%s", output)
+	}
+	if !exists(handMade) {
+		t.Fatalf("dry run moved %s", handMade)
+	}
+
+	ac = NewAdoptCommand(gs)
+	ac.SetAll(true)
+	ac.SetYes(true)
+	if output, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("adopt failed: %v\n%s", err, output)
+	}
+	for from, to := range wantMoves {
+		if exists(from) || !exists(filepath.Join(to, "README.md")) {
+			t.Errorf("%s was not moved to %s", from, to)
+		}
+	}
+	if !exists(locked) {
+		t.Errorf("locked worktree was moved")
+	}
+	if branch := runTestGit(t, wantMoves[handMade], "symbolic-ref", "--short", "HEAD"); branch != "feature/a" {
+		t.Errorf("adopted worktree is on %q, want feature/a", branch)
+	}
+}
+
+func TestAdoptArguments(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	for _, tt := range []struct {
+		worktrees []string
+		all       bool
+		wantErr   string
+	}{
+		{nil, false, "or --all"},
+		{[]string{"x"}, true, "or --all"},
+		{[]string{"missing"}, false, "no worktree named"},
+		{[]string{repoDir}, false, "main worktree"},
+	} {
+		ac := NewAdoptCommand(gs)
+		ac.SetWorktrees(tt.worktrees)
+		ac.SetAll(tt.all)
+		if err := ac.Execute(repoDir); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("adopt %v (all=%v): error = %v, want %q", tt.worktrees, tt.all, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAdoptCancelsWithoutAnswer(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+	handMade := filepath.Join(t.TempDir(), "scratch")
+	runTestGit(t, repoDir, "worktree", "add", "-q", "-b", "scratch", handMade)
+
+	// Nobody answers when stdin is /dev/null or a closed pipe
+	ac := NewAdoptCommand(gs)
+	ac.SetAll(true)
+	ac.SetInput(strings.NewReader(""))
+	output, err := captureStdout(t, func() error { return ac.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("adopt failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Cancelled") {
+		t.Errorf("expected the move to be cancelled:\n%s", output)
+	}
+	if !exists(handMade) || exists(filepath.Join(gs.GetWorktreesDir(repoDir), "scratch")) {
+		t.Errorf("worktree was moved without confirmation")
+	}
+}
@@ -0,0 +1,179 @@
+// Clone and init commands for the bare repository ("hub") layout
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// hubGitDir is the name of the bare repository inside a hub directory. The hub
+// also holds a .git file pointing at it, so that git and wt work from the hub,
+// and the worktrees as siblings.
+const hubGitDir = ".bare"
+
+// CloneCommand handles the 'wt clone' and 'wt init --bare-hub' commands
+type CloneCommand struct {
+	gitService *services.GitService
+	url        string // Repository to clone; empty creates an empty repository
+	dir        string // Hub directory
+}
+
+// NewCloneCommand creates a new CloneCommand instance
+func NewCloneCommand(gitService *services.GitService) *CloneCommand {
+	return &CloneCommand{
+		gitService: gitService,
+	}
+}
+
+// SetURL sets the repository to clone
+func (cc *CloneCommand) SetURL(url string) {
+	cc.url = url
+}
+
+// SetDir sets the hub directory
+func (cc *CloneCommand) SetDir(dir string) {
+	cc.dir = dir
+}
+
+// Execute creates the hub: a bare clone of the URL, or an empty bare repository,
+// in <dir>/.bare, the .git file pointing at it and a worktree for the default
+// branch. If a step fails, everything that was created is removed again.
+func (cc *CloneCommand) Execute() error {
+	dir, err := filepath.Abs(cc.dir)
+	if err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty", dir)
+	}
+
+	tx := &transaction{}
+	if err := cc.execute(dir, tx); err != nil {
+		if rbErr := tx.rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback incomplete: %v)", err, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// execute performs the steps of Execute, recording each change in tx
+func (cc *CloneCommand) execute(dir string, tx *transaction) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		tx.record("remove "+dir, func() error { return os.RemoveAll(dir) })
+	}
+
+	gitDir := filepath.Join(dir, hubGitDir)
+	if cc.url != "" {
+		fmt.Printf("Cloning %s into %s\n", cc.url, gitDir)
+		err := cc.gitService.CloneBare(cc.url, gitDir)
+		// A failed clone may leave a partial repository behind
+		tx.record("remove "+gitDir, func() error { return os.RemoveAll(gitDir) })
+		if err != nil {
+			return err
+		}
+	} else {
+		if err := cc.gitService.InitBare(gitDir); err != nil {
+			return err
+		}
+		tx.record("remove "+gitDir, func() error { return os.RemoveAll(gitDir) })
+	}
+
+	gitFile := filepath.Join(dir, ".git")
+	if err := os.WriteFile(gitFile, []byte("gitdir: ./"+hubGitDir+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", gitFile, err)
+	}
+	tx.record("remove "+gitFile, func() error { return os.Remove(gitFile) })
+	fmt.Printf("✓ Created hub %s\n", dir)
+
+	branch, err := cc.gitService.GetHeadBranch(gitDir)
+	if err != nil {
+		return err
+	}
+	if _, err := cc.gitService.ResolveRef(gitDir, branch); err != nil {
+		// An empty repository: the default branch has no commits to check out
+		return cc.addUnbornWorktree(dir, gitDir, branch)
+	}
+
+	if cc.url != "" {
+		if err := cc.trackRemoteBranches(gitDir, branch); err != nil {
+			return err
+		}
+	}
+	return cc.addWorktree(dir, gitDir, branch, false)
+}
+
+// trackRemoteBranches makes the default branch track its remote branch.
+// 'git clone --bare' copies every branch as a local branch; the others are
+// deleted so that 'wt add <branch>' creates them tracking the remote instead.
+func (cc *CloneCommand) trackRemoteBranches(gitDir, defaultBranch string) error {
+	branches, err := cc.gitService.GetLocalBranches(gitDir)
+	if err != nil {
+		return err
+	}
+	for _, branch := range branches {
+		if branch == defaultBranch {
+			continue
+		}
+		if err := cc.gitService.DeleteBranch(gitDir, branch); err != nil {
+			return err
+		}
+	}
+	return cc.gitService.SetUpstream(gitDir, defaultBranch, "origin/"+defaultBranch)
+}
+
+// addUnbornWorktree creates the worktree of the default branch of an empty
+// repository, which requires 'git worktree add --orphan'
+func (cc *CloneCommand) addUnbornWorktree(dir, gitDir, branch string) error {
+	if !cc.gitService.HasCapability(models.CapAddOrphan) {
+		fmt.Printf("The repository is empty; with git >= %s, run 'wt add --orphan %s' in %s to create the first worktree\n",
+			models.CapAddOrphan.MinVersion, branch, dir)
+		return nil
+	}
+	return cc.addWorktree(dir, gitDir, branch, true)
+}
+
+// addWorktree adds the worktree of branch to the hub with 'wt add'
+func (cc *CloneCommand) addWorktree(dir, gitDir, branch string, orphan bool) error {
+	ac := NewAddCommand(cc.gitService)
+	ac.SetBranchName(branch)
+	ac.SetOrphan(orphan)
+	if err := ac.Execute(gitDir); err != nil {
+		return err
+	}
+	fmt.Printf("Run 'wt add <branch>' in %s to add more worktrees\n", dir)
+	return nil
+}
+
+// CloneDirFromURL returns the directory 'git clone' would use for url, e.g.
+// "app" for git@example.com:team/app.git
+func CloneDirFromURL(url string) string {
+	url = strings.TrimRight(url, "/")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	return strings.TrimSuffix(url, ".git")
+}
+
+// RunCloneCommand is the entry point for the clone command
+func RunCloneCommand(gitPath, url, dir string) error {
+	gitService := services.NewGitService(gitPath)
+	cloneCmd := NewCloneCommand(gitService)
+	cloneCmd.SetURL(url)
+	cloneCmd.SetDir(dir)
+
+	return cloneCmd.Execute()
+}
+
+// RunInitCommand is the entry point for 'wt init --bare-hub'
+func RunInitCommand(gitPath, dir string) error {
+	return RunCloneCommand(gitPath, "", dir)
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+func TestCloneCreatesHub(t *testing.T) {
+	srcDir := newTestRepo(t)
+	runTestGit(t, srcDir, "branch", "feature")
+	defaultBranch := runTestGit(t, srcDir, "symbolic-ref", "--short", "HEAD")
+	gs := services.NewGitService("")
+
+	hub := filepath.Join(t.TempDir(), "hub")
+	cc := NewCloneCommand(gs)
+	cc.SetURL(srcDir)
+	cc.SetDir(hub)
+	if output, err := captureStdout(t, func() error { return cc.Execute() }); err != nil {
+		t.Fatalf("clone failed: %v\n%s", err, output)
+	}
+
+	gitDir := filepath.Join(hub, hubGitDir)
+	if !gs.IsBareRepository(hub) {
+		t.Errorf("hub is not a bare repository")
+	}
+	if got := gs.GetWorktreesDir(gitDir); !samePath(got, hub) {
+		t.Errorf("worktrees dir = %s, want %s", got, hub)
+	}
+	wtPath := filepath.Join(hub, defaultBranch)
+	if !exists(filepath.Join(wtPath, "README.md")) {
+		t.Fatalf("default branch was not checked out in %s", wtPath)
+	}
+	if upstream := gs.GetUpstream(gitDir, defaultBranch); upstream != "origin/"+defaultBranch {
+		t.Errorf("upstream = %q, want origin/%s", upstream, defaultBranch)
+	}
+	if branches, _ := gs.GetLocalBranches(gitDir); len(branches) != 1 {
+		t.Errorf("local branches = %v, want only %s", branches, defaultBranch)
+	}
+
+	// Other branches are checked out tracking the remote
+	ac := NewAddCommand(gs)
+	ac.SetBranchName("feature")
+	if output, err := captureStdout(t, func() error { return ac.Execute(gitDir) }); err != nil {
+		t.Fatalf("add failed: %v\n%s", err, output)
+	}
+	if upstream := gs.GetUpstream(gitDir, "feature"); upstream != "origin/feature" {
+		t.Errorf("feature upstream = %q, want origin/feature", upstream)
+	}
+
+	// The bare repository is not reported as a worktree with changes
+	sc := NewStatusCommand(gs)
+	statuses, err := sc.collect(gitDir)
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Errorf("status reported %d worktrees, want 2", len(statuses))
+	}
+}
+
+func TestCloneRollsBackOnFailure(t *testing.T) {
+	newTestRepo(t)
+	gs := services.NewGitService("")
+	hub := filepath.Join(t.TempDir(), "hub")
+
+	cc := NewCloneCommand(gs)
+	cc.SetURL(filepath.Join(t.TempDir(), "missing"))
+	cc.SetDir(hub)
+	if _, err := captureStdout(t, func() error { return cc.Execute() }); err == nil {
+		t.Fatalf("expected clone of a missing repository to fail")
+	}
+	if exists(hub) {
+		t.Errorf("%s was left behind", hub)
+	}
+
+	// A non-empty directory is refused
+	if err := os.MkdirAll(hub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(hub, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cc.Execute(); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("expected non-empty directory to be refused, got %v", err)
+	}
+}
+
+func TestCloneDirFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/team/app.git": "app",
+		"git@github.com:team/app.git":     "app",
+		"/srv/git/app/":                   "app",
+		"host:app":                        "app",
+	}
+	for url, want := range tests {
+		if got := CloneDirFromURL(url); got != want {
+			t.Errorf("CloneDirFromURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
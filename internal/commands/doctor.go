@@ -0,0 +1,248 @@
+// Doctor command implementation
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// DoctorCommand handles the 'wt doctor' command
+type DoctorCommand struct {
+	gitService *services.GitService
+	jsonOutput bool
+}
+
+// NewDoctorCommand creates a new DoctorCommand instance
+func NewDoctorCommand(gitService *services.GitService) *DoctorCommand {
+	return &DoctorCommand{
+		gitService: gitService,
+	}
+}
+
+// SetJSONOutput sets JSON output mode
+func (dc *DoctorCommand) SetJSONOutput(jsonOutput bool) {
+	dc.jsonOutput = jsonOutput
+}
+
+// Execute runs all checks and prints the results.
+// It returns an error if any check failed with an error.
+func (dc *DoctorCommand) Execute(repoPath string) error {
+	results := dc.RunChecks(repoPath)
+
+	if dc.jsonOutput {
+		output, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format results as JSON: %w", err)
+		}
+		fmt.Println(string(output))
+	} else {
+		fmt.Print(formatCheckResults(results))
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Status == models.CheckError {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", failed)
+	}
+	return nil
+}
+
+// RunChecks runs every diagnostic check against the repository
+func (dc *DoctorCommand) RunChecks(repoPath string) []models.CheckResult {
+	var results []models.CheckResult
+	results = append(results, dc.checkGitVersion())
+	results = append(results, dc.checkDefaultRef(repoPath))
+	results = append(results, dc.checkRemotes(repoPath))
+	results = append(results, dc.checkWorktreesDir(repoPath))
+	results = append(results, dc.checkConfig(repoPath))
+	results = append(results, dc.checkWorktrees(repoPath)...)
+	return results
+}
+
+// checkGitVersion verifies that the installed git is recent enough
+func (dc *DoctorCommand) checkGitVersion() models.CheckResult {
+	version, err := dc.gitService.ParsedGitVersion()
+	if err != nil {
+		return models.NewCheckResult("git version", models.CheckError, err.Error(), "install git and make sure it is on your PATH")
+	}
+	if !version.AtLeast(models.MinGitVersion) {
+		return models.NewCheckResult("git version", models.CheckError,
+			fmt.Sprintf("git %s is too old", version),
+			fmt.Sprintf("upgrade git to %s or newer", models.MinGitVersion))
+	}
+
+	// Missing capabilities are not a problem: wt falls back or explains what is needed
+	message := "git " + version.String()
+	var missing []string
+	for _, c := range models.Capabilities {
+		if !c.SupportedBy(version) {
+			missing = append(missing, c.Name)
+		}
+	}
+	if len(missing) > 0 {
+		message += " (without " + strings.Join(missing, ", ") + ")"
+	}
+	return models.NewCheckResult("git version", models.CheckOK, message, "")
+}
+
+// checkDefaultRef verifies that 'wt add -b' can find a branch to start from
+func (dc *DoctorCommand) checkDefaultRef(repoPath string) models.CheckResult {
+	base := dc.gitService.GetBaseRemote(repoPath)
+	ref, err := dc.gitService.GetDefaultRef(repoPath)
+	if err != nil {
+		return models.NewCheckResult("default ref", models.CheckError, err.Error(),
+			fmt.Sprintf("create an initial commit or run 'git remote set-head %s --auto'", base))
+	}
+
+	remotes, _ := dc.gitService.GetRemotes(repoPath)
+	if slices.Contains(remotes, base) {
+		if _, err := dc.gitService.GetRemoteHead(repoPath, base); err != nil {
+			return models.NewCheckResult("default ref", models.CheckWarning,
+				fmt.Sprintf("%s/HEAD is not set, falling back to %s", base, ref),
+				fmt.Sprintf("run 'git remote set-head %s --auto'", base))
+		}
+	}
+	return models.NewCheckResult("default ref", models.CheckOK, "new branches start from "+ref, "")
+}
+
+// checkRemotes verifies that the configured base and push remotes exist
+func (dc *DoctorCommand) checkRemotes(repoPath string) models.CheckResult {
+	remotes, err := dc.gitService.GetRemotes(repoPath)
+	if err != nil {
+		return models.NewCheckResult("remotes", models.CheckError, err.Error(), "")
+	}
+	if len(remotes) == 0 {
+		return models.NewCheckResult("remotes", models.CheckOK, "no remotes configured", "")
+	}
+
+	base := dc.gitService.GetBaseRemote(repoPath)
+	push := dc.gitService.GetPushRemote(repoPath)
+	for _, remote := range []string{base, push} {
+		if !slices.Contains(remotes, remote) {
+			return models.NewCheckResult("remotes", models.CheckError,
+				fmt.Sprintf("remote %q does not exist (have: %s)", remote, strings.Join(remotes, ", ")),
+				"set wt.baseRemote and wt.pushRemote to existing remotes")
+		}
+	}
+	return models.NewCheckResult("remotes", models.CheckOK,
+		fmt.Sprintf("branches start from %s and are pushed to %s", base, push), "")
+}
+
+// checkWorktreesDir verifies that new worktrees can be created in the worktrees directory
+func (dc *DoctorCommand) checkWorktreesDir(repoPath string) models.CheckResult {
+	dir := dc.gitService.GetWorktreesDir(repoPath)
+
+	// Check the directory itself or, if it does not exist yet, the closest existing parent
+	existing := dir
+	for {
+		if _, err := os.Stat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+
+	probe, err := os.CreateTemp(existing, ".wt-doctor-*")
+	if err != nil {
+		return models.NewCheckResult("worktrees directory", models.CheckError,
+			fmt.Sprintf("%s is not writable: %v", existing, err),
+			fmt.Sprintf("fix the permissions of %s", existing))
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return models.NewCheckResult("worktrees directory", models.CheckOK, dir+" is writable", "")
+}
+
+// checkConfig verifies that the wt.* settings in git config are valid
+func (dc *DoctorCommand) checkConfig(repoPath string) models.CheckResult {
+	if _, err := dc.gitService.LoadConfig(repoPath); err != nil {
+		return models.NewCheckResult("config", models.CheckError, err.Error(),
+			"fix the value with 'git config <key> <value>' or remove it with 'git config --unset <key>'")
+	}
+	return models.NewCheckResult("config", models.CheckOK, "wt settings are valid", "")
+}
+
+// checkWorktrees reports stale registrations, unregistered directories and locked worktrees
+func (dc *DoctorCommand) checkWorktrees(repoPath string) []models.CheckResult {
+	worktrees, err := dc.gitService.GetWorktrees(repoPath)
+	if err != nil {
+		return []models.CheckResult{models.NewCheckResult("worktrees", models.CheckError, err.Error(), "")}
+	}
+
+	var results []models.CheckResult
+	var locked []string
+	stale := 0
+	for _, wt := range worktrees {
+		if wt.IsPrunable() {
+			stale++
+			results = append(results, models.NewCheckResult("stale worktree", models.CheckWarning,
+				fmt.Sprintf("%s: %s", wt.Path, wt.PrunableReason),
+				"run 'wt prune' (it repairs worktrees that were moved by hand)"))
+		}
+		if wt.IsLocked {
+			locked = append(locked, wt.Path)
+		}
+	}
+
+	orphans, err := dc.gitService.FindUnregisteredWorktrees(repoPath)
+	if err == nil {
+		for _, dir := range orphans {
+			results = append(results, models.NewCheckResult("orphaned directory", models.CheckWarning,
+				dir+" looks like a worktree but is not registered with git",
+				fmt.Sprintf("run 'git worktree repair %s' if it was moved, otherwise delete it", dir)))
+		}
+	}
+
+	if len(locked) > 0 {
+		results = append(results, models.NewCheckResult("locked worktrees", models.CheckOK,
+			strings.Join(locked, ", "), ""))
+	}
+	if stale == 0 && len(orphans) == 0 {
+		results = append(results, models.NewCheckResult("worktrees", models.CheckOK,
+			fmt.Sprintf("%d worktree(s) registered, none stale", len(worktrees)), ""))
+	}
+	return results
+}
+
+// formatCheckResults renders check results for the terminal
+func formatCheckResults(results []models.CheckResult) string {
+	var result strings.Builder
+	for _, r := range results {
+		mark := "✓"
+		switch r.Status {
+		case models.CheckWarning:
+			mark = "!"
+		case models.CheckError:
+			mark = "✗"
+		}
+		result.WriteString(fmt.Sprintf("%s %s: %s\n", mark, r.Name, r.Message))
+		if r.Fix != "" {
+			result.WriteString(fmt.Sprintf("    fix: %s\n", r.Fix))
+		}
+	}
+	return result.String()
+}
+
+// RunDoctorCommand is the entry point for the doctor command
+func RunDoctorCommand(repoPath, gitPath string, jsonOutput bool) error {
+	gitService := services.NewGitService(gitPath)
+	doctorCmd := NewDoctorCommand(gitService)
+	doctorCmd.SetJSONOutput(jsonOutput)
+
+	return doctorCmd.Execute(repoPath)
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+func TestDoctorFindsProblems(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	// A directory with a .git file that git does not know about
+	orphan := filepath.Join(gs.GetWorktreesDir(repoDir), "orphan")
+	if err := os.MkdirAll(orphan, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(orphan, ".git"), []byte("gitdir: /nowhere\n"), 0644); err != nil {
+		t.Fatalf("write .git: %v", err)
+	}
+	runTestGit(t, repoDir, "config", "wt.gc.olderThan", "someday")
+
+	results := NewDoctorCommand(gs).RunChecks(repoDir)
+	statuses := map[string]models.CheckStatus{}
+	for _, r := range results {
+		statuses[r.Name] = r.Status
+	}
+
+	if statuses["git version"] != models.CheckOK {
+		t.Errorf("git version status = %q, want ok", statuses["git version"])
+	}
+	if statuses["config"] != models.CheckError {
+		t.Errorf("config status = %q, want error", statuses["config"])
+	}
+	if statuses["orphaned directory"] != models.CheckWarning {
+		t.Errorf("orphaned directory status = %q, want warning", statuses["orphaned directory"])
+	}
+
+	if _, err := captureStdout(t, func() error { return NewDoctorCommand(gs).Execute(repoDir) }); err == nil {
+		t.Errorf("expected Execute to fail when a check reports an error")
+	}
+}
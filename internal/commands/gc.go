@@ -0,0 +1,211 @@
+// GC command implementation
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+	"github.com/smoerfugl/wt/internal/utils"
+)
+
+// GCCommand handles the 'wt gc' command
+type GCCommand struct {
+	gitService *services.GitService
+	olderThan  time.Duration
+	ageSet     bool // Whether SetOlderThan was called, so that an explicit zero is kept
+	report     bool
+	force      bool
+	now        func() time.Time
+}
+
+// gcCandidate is a worktree that has been idle for longer than the threshold
+type gcCandidate struct {
+	worktree     models.Worktree
+	lastActivity time.Time
+	skipReason   string // Why the worktree is kept; empty if it will be removed
+}
+
+// NewGCCommand creates a new GCCommand instance
+func NewGCCommand(gitService *services.GitService) *GCCommand {
+	return &GCCommand{
+		gitService: gitService,
+		now:        time.Now,
+	}
+}
+
+// SetOlderThan sets the idle age after which worktrees are collected, overriding
+// wt.gc.olderThan. Zero collects every idle worktree.
+func (gc *GCCommand) SetOlderThan(olderThan time.Duration) {
+	gc.olderThan = olderThan
+	gc.ageSet = true
+}
+
+// SetReport sets report mode, which lists candidates without removing them
+func (gc *GCCommand) SetReport(report bool) {
+	gc.report = report
+}
+
+// SetForce sets whether worktrees with uncommitted changes are removed too
+func (gc *GCCommand) SetForce(force bool) {
+	gc.force = force
+}
+
+// Execute runs the gc command
+func (gc *GCCommand) Execute(repoPath string) error {
+	cfg, err := gc.gitService.LoadConfig(repoPath)
+	if err != nil {
+		return err
+	}
+
+	olderThan := gc.olderThan
+	if !gc.ageSet {
+		if cfg.GCOlderThan <= 0 {
+			return fmt.Errorf("no age given: use --older-than or set wt.gc.olderThan")
+		}
+		olderThan = cfg.GCOlderThan
+	}
+
+	worktrees, err := gc.gitService.GetWorktrees(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to get worktrees: %w", err)
+	}
+
+	candidates := gc.findCandidates(repoPath, worktrees, olderThan, cfg.GCKeep)
+	if len(candidates) == 0 {
+		fmt.Printf("No worktrees idle for more than %s\n", utils.FormatAge(olderThan))
+		return nil
+	}
+
+	if gc.report {
+		fmt.Print(gc.formatReport(candidates))
+		return nil
+	}
+
+	removed := 0
+	for _, c := range candidates {
+		if c.skipReason != "" {
+			fmt.Printf("Skipped %s: %s\n", c.worktree.Path, c.skipReason)
+			continue
+		}
+		if err := gc.gitService.RemoveWorktree(repoPath, c.worktree.Path, gc.force); err != nil {
+			fmt.Printf("✗ %s: %v\n", c.worktree.Path, err)
+			continue
+		}
+		fmt.Printf("✓ Removed %s (idle %s)\n", c.worktree.Path, utils.FormatAge(gc.now().Sub(c.lastActivity)))
+		removed++
+	}
+
+	fmt.Printf("Removed %d of %d idle worktree(s)\n", removed, len(candidates))
+	return nil
+}
+
+// findCandidates returns the linked worktrees that have been idle for longer than olderThan
+func (gc *GCCommand) findCandidates(repoPath string, worktrees []models.Worktree, olderThan time.Duration, keep []string) []*gcCandidate {
+	var candidates []*gcCandidate
+	cutoff := gc.now().Add(-olderThan)
+
+	for i, wt := range worktrees {
+		// The main worktree is never collected
+		if i == 0 || wt.Path == repoPath {
+			continue
+		}
+		if _, err := os.Stat(wt.Path); err != nil {
+			// Missing directories are handled by 'wt prune'
+			continue
+		}
+
+		lastActivity, err := gc.lastActivity(wt.Path)
+		if err != nil || lastActivity.After(cutoff) {
+			continue
+		}
+
+		c := &gcCandidate{worktree: wt, lastActivity: lastActivity}
+		if wt.IsLocked {
+			c.skipReason = wt.DescribeLock()
+		} else if pattern := matchKeepPattern(wt.Branch, keep); pattern != "" {
+			c.skipReason = fmt.Sprintf("kept by wt.gc.keep %q", pattern)
+		} else if !gc.force {
+			// Uncommitted work is only thrown away when asked to explicitly
+			if clean, err := gc.gitService.IsWorktreeClean(wt.Path); err != nil {
+				c.skipReason = err.Error()
+			} else if !clean {
+				c.skipReason = "has uncommitted changes (use --force to remove anyway)"
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates
+}
+
+// lastActivity returns the later of the last commit time and the filesystem modification time
+func (gc *GCCommand) lastActivity(worktreePath string) (time.Time, error) {
+	modified, err := gc.gitService.GetLastModified(worktreePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	committed, err := gc.gitService.GetLastCommitTime(worktreePath)
+	if err == nil && committed.After(modified) {
+		return committed, nil
+	}
+	return modified, nil
+}
+
+// matchKeepPattern returns the first pattern matching branch, or "" if none does
+func matchKeepPattern(branch string, patterns []string) string {
+	if branch == "" {
+		return ""
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// formatReport renders the candidates as a table without acting on them
+func (gc *GCCommand) formatReport(candidates []*gcCandidate) string {
+	pathWidth := len("PATH")
+	branchWidth := len("BRANCH")
+	for _, c := range candidates {
+		if len(c.worktree.Path) > pathWidth {
+			pathWidth = len(c.worktree.Path)
+		}
+		if len(c.worktree.Branch) > branchWidth {
+			branchWidth = len(c.worktree.Branch)
+		}
+	}
+
+	result := fmt.Sprintf("%-*s  %-*s  %-6s  %s\n", pathWidth, "PATH", branchWidth, "BRANCH", "IDLE", "ACTION")
+	for _, c := range candidates {
+		action := "remove"
+		if c.skipReason != "" {
+			action = "keep (" + c.skipReason + ")"
+		}
+		result += fmt.Sprintf("%-*s  %-*s  %-6s  %s\n",
+			pathWidth, c.worktree.Path,
+			branchWidth, c.worktree.Branch,
+			utils.FormatAge(gc.now().Sub(c.lastActivity)),
+			action)
+	}
+	return result
+}
+
+// RunGCCommand is the entry point for the gc command. A nil olderThan uses
+// wt.gc.olderThan.
+func RunGCCommand(repoPath, gitPath string, olderThan *time.Duration, report, force bool) error {
+	gitService := services.NewGitService(gitPath)
+	gcCmd := NewGCCommand(gitService)
+	if olderThan != nil {
+		gcCmd.SetOlderThan(*olderThan)
+	}
+	gcCmd.SetReport(report)
+	gcCmd.SetForce(force)
+
+	return gcCmd.Execute(repoPath)
+}
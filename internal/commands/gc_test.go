@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+func TestMatchKeepPattern(t *testing.T) {
+	patterns := []string{"release/*", "main"}
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"release/1.0", "release/*"},
+		{"main", "main"},
+		{"feature/login", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := matchKeepPattern(tt.branch, patterns); got != tt.want {
+			t.Errorf("matchKeepPattern(%q) = %q, want %q", tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestGCFindCandidates(t *testing.T) {
+	repoDir := newTestRepo(t)
+	base := filepath.Dir(repoDir)
+	for _, branch := range []string{"stale", "locked", "release-1"} {
+		runTestGit(t, repoDir, "worktree", "add", "-b", branch, filepath.Join(base, branch))
+	}
+	runTestGit(t, repoDir, "worktree", "lock", filepath.Join(base, "locked"))
+
+	gs := services.NewGitService("")
+	worktrees, err := gs.GetWorktrees(repoDir)
+	if err != nil {
+		t.Fatalf("GetWorktrees failed: %v", err)
+	}
+
+	gc := NewGCCommand(gs)
+
+	// Nothing is idle yet
+	if got := gc.findCandidates(repoDir, worktrees, 24*time.Hour, nil); len(got) != 0 {
+		t.Fatalf("expected no candidates, got %d", len(got))
+	}
+
+	// Pretend 60 days have passed
+	gc.now = func() time.Time { return time.Now().Add(60 * 24 * time.Hour) }
+	candidates := gc.findCandidates(repoDir, worktrees, 30*24*time.Hour, []string{"release-*"})
+	if len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(candidates))
+	}
+
+	reasons := map[string]string{}
+	for _, c := range candidates {
+		reasons[c.worktree.Branch] = c.skipReason
+	}
+	if reasons["stale"] != "" {
+		t.Errorf("stale worktree should be removed, got skip reason %q", reasons["stale"])
+	}
+	if reasons["locked"] != "locked" {
+		t.Errorf("locked worktree skip reason = %q, want locked", reasons["locked"])
+	}
+	if reasons["release-1"] == "" {
+		t.Errorf("release-1 should be kept by wt.gc.keep")
+	}
+}
+
+func TestGCNestedChangesAndDirtyWorktrees(t *testing.T) {
+	repoDir := newTestRepo(t)
+	base := filepath.Dir(repoDir)
+	for _, branch := range []string{"nested", "dirty"} {
+		runTestGit(t, repoDir, "worktree", "add", "-b", branch, filepath.Join(base, branch))
+	}
+
+	gs := services.NewGitService("")
+	gc := NewGCCommand(gs)
+	later := time.Now().Add(60 * 24 * time.Hour)
+	gc.now = func() time.Time { return later }
+
+	// An edit deep in the tree counts as activity
+	nested := filepath.Join(base, "nested", "src", "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(nested, "work.go")
+	if err := os.WriteFile(file, []byte("package pkg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "dirty", "README.md"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	worktrees, err := gs.GetWorktrees(repoDir)
+	if err != nil {
+		t.Fatalf("GetWorktrees failed: %v", err)
+	}
+	candidates := gc.findCandidates(repoDir, worktrees, 30*24*time.Hour, nil)
+	if len(candidates) != 1 || candidates[0].worktree.Branch != "dirty" {
+		t.Fatalf("expected only the dirty worktree as a candidate, got %d", len(candidates))
+	}
+	if !strings.Contains(candidates[0].skipReason, "uncommitted changes") {
+		t.Errorf("dirty worktree skip reason = %q", candidates[0].skipReason)
+	}
+
+	gc.SetForce(true)
+	if candidates := gc.findCandidates(repoDir, worktrees, 30*24*time.Hour, nil); candidates[0].skipReason != "" {
+		t.Errorf("--force should remove the dirty worktree, got skip reason %q", candidates[0].skipReason)
+	}
+}
+
+func TestGCExplicitZeroAge(t *testing.T) {
+	repoDir := newTestRepo(t)
+	idle := filepath.Join(filepath.Dir(repoDir), "idle")
+	runTestGit(t, repoDir, "worktree", "add", "-q", "-b", "idle", idle)
+	gs := services.NewGitService("")
+	later := time.Now().Add(time.Hour)
+
+	gc := NewGCCommand(gs)
+	gc.SetReport(true)
+	if _, err := captureStdout(t, func() error { return gc.Execute(repoDir) }); err == nil || !strings.Contains(err.Error(), "no age given") {
+		t.Errorf("expected an error without an age, got %v", err)
+	}
+
+	// --older-than 0d overrides the configured age instead of falling back to it
+	runTestGit(t, repoDir, "config", "wt.gc.olderThan", "30d")
+	gc = NewGCCommand(gs)
+	gc.now = func() time.Time { return later }
+	gc.SetReport(true)
+	gc.SetOlderThan(0)
+	output, err := captureStdout(t, func() error { return gc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("gc --older-than 0d failed: %v", err)
+	}
+	if !strings.Contains(output, idle) {
+		t.Errorf("expected %s to be collected with a zero age:\n%s", idle, output)
+	}
+}
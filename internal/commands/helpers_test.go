@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a repository with one commit in a temporary directory
+// and returns its path. Tests are skipped when git is not installed. The global
+// git config is replaced by an empty one for the duration of the test.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed in test environment")
+	}
+	// wt add registers repositories in the global git config; keep the user's out of it
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	if err := os.Mkdir(repoDir, 0755); err != nil {
+		t.Fatalf("mkdir repo: %v", err)
+	}
+	runTestGit(t, repoDir, "init")
+	runTestGit(t, repoDir, "config", "user.email", "test@example.com")
+	runTestGit(t, repoDir, "config", "user.name", "Test")
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runTestGit(t, repoDir, "add", ".")
+	runTestGit(t, repoDir, "commit", "-m", "init")
+	return repoDir
+}
+
+// runTestGit runs git in dir and fails the test on error
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// captureStdout runs fn and returns what it printed to stdout
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	os.Stdout = w
+
+	done := make(chan string)
+	go func() {
+		var buf strings.Builder
+		io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+
+	fnErr := fn()
+	w.Close()
+	os.Stdout = old
+	return <-done, fnErr
+}
+
+// addTestRemote creates a bare repository, registers it as remote name of
+// repoDir and pushes the current branch to it. It returns the bare repository path.
+func addTestRemote(t *testing.T, repoDir, name string) string {
+	t.Helper()
+	remoteDir := filepath.Join(t.TempDir(), name+".git")
+	runTestGit(t, repoDir, "init", "--bare", remoteDir)
+	runTestGit(t, repoDir, "remote", "add", name, remoteDir)
+	runTestGit(t, repoDir, "push", "-q", name, "HEAD")
+	return remoteDir
+}
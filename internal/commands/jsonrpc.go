@@ -0,0 +1,95 @@
+// JSON-RPC 2.0 messages for 'wt serve'
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Error codes defined by the JSON-RPC 2.0 specification, and the one used when
+// a wt operation fails
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	rpcCommandFailed  = -32000
+)
+
+// rpcRequest is a request, or a notification if it has no id
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// rpcResponse carries either a result or an error. A nil ID is sent as null.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcNotification is a message from the server that expects no response
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// rpcError is the error object of a failed request
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// newRPCError creates an error with a formatted message
+func newRPCError(code int, format string, args ...any) *rpcError {
+	return &rpcError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// decodeParams unmarshals the named parameters of a request into v. Absent
+// parameters leave v at its zero value; positional parameters are not supported.
+func decodeParams(params json.RawMessage, v any) *rpcError {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return nil
+	}
+	if params[0] != '{' {
+		return newRPCError(rpcInvalidParams, "params must be an object")
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return newRPCError(rpcInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+// rpcWriter writes one JSON message per line. It is safe for concurrent use, so
+// responses and notifications can be sent from different goroutines.
+type rpcWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	timeout time.Duration // How long a write to a connection may block; 0 is forever
+}
+
+// write sends a single message or batch
+func (rw *rpcWriter) write(message any) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if conn, ok := rw.w.(interface{ SetWriteDeadline(time.Time) error }); ok && rw.timeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(rw.timeout))
+	}
+	_, err = rw.w.Write(append(encoded, '\n'))
+	return err
+}
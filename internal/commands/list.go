@@ -3,6 +3,7 @@ package commands
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/smoerfugl/wt/internal/models"
@@ -17,6 +18,7 @@ type ListCommand struct {
 	filter     string
 	branch     string
 	jsonOutput bool
+	allRepos   bool
 }
 
 // NewListCommand creates a new ListCommand instance
@@ -28,28 +30,91 @@ func NewListCommand(gitService *services.GitService) *ListCommand {
 
 // Execute runs the list command
 func (lc *ListCommand) Execute(repoPath string) error {
+	if lc.allRepos {
+		return lc.executeAllRepos()
+	}
 	worktrees, err := lc.gitService.GetWorktrees(repoPath)
 	if err != nil {
 		return fmt.Errorf("failed to get worktrees: %w", err)
 	}
+	lc.Print(lc.applyFilters(worktrees), repoPath)
+	return nil
+}
+
+// Print writes worktrees that were already filtered in the selected output format
+func (lc *ListCommand) Print(worktrees []models.Worktree, repoPath string) {
+	if lc.verbose && !lc.jsonOutput {
+		lc.checkSubmodules(worktrees)
+	}
+	fmt.Print(lc.format(worktrees, repoPath))
+}
+
+// Worktrees returns the worktrees of the repository that pass the filters
+func (lc *ListCommand) Worktrees(repoPath string) ([]models.Worktree, error) {
+	worktrees, err := lc.gitService.GetWorktrees(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	return lc.applyFilters(worktrees), nil
+}
+
+// executeAllRepos lists the worktrees of every registered repository, one
+// section per repository. Repositories that cannot be read are reported but do
+// not stop the others from being listed.
+func (lc *ListCommand) executeAllRepos() error {
+	results, err := forEachRepo(lc.gitService, func(repo models.RegisteredRepo) ([]models.Worktree, error) {
+		worktrees, err := lc.gitService.GetWorktrees(repo.Path)
+		if err != nil {
+			return nil, err
+		}
+		return lc.collect(worktrees), nil
+	})
+	if err != nil {
+		return err
+	}
 
-	// Apply filters
-	filteredWorktrees := lc.applyFilters(worktrees)
+	var sections []string
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s (%s): %v\n", r.repo.Name, r.repo.Path, r.err)
+			continue
+		}
+		if lc.jsonOutput {
+			sections = append(sections, strings.TrimRight(lc.format(r.value, r.repo.Path), "\n"))
+		} else {
+			sections = append(sections, fmt.Sprintf("%s (%s)\n%s", r.repo.Name, r.repo.Path, lc.format(r.value, r.repo.Path)))
+		}
+	}
 
-	// Format output
-	var output string
 	if lc.jsonOutput {
-		output = utils.FormatJSON(filteredWorktrees, repoPath)
-	} else if lc.verbose {
-		output = utils.FormatVerbose(filteredWorktrees)
+		fmt.Printf("[\n%s\n]\n", strings.Join(sections, ",\n"))
 	} else {
-		output = utils.FormatBasic(filteredWorktrees)
+		fmt.Print(strings.Join(sections, "\n"))
 	}
-
-	fmt.Print(output)
 	return nil
 }
 
+// collect applies the filters and, in verbose mode, the submodule check
+func (lc *ListCommand) collect(worktrees []models.Worktree) []models.Worktree {
+	filtered := lc.applyFilters(worktrees)
+	if lc.verbose && !lc.jsonOutput {
+		lc.checkSubmodules(filtered)
+	}
+	return filtered
+}
+
+// format renders worktrees in the selected output format
+func (lc *ListCommand) format(worktrees []models.Worktree, repoPath string) string {
+	switch {
+	case lc.jsonOutput:
+		return utils.FormatJSON(worktrees, repoPath)
+	case lc.verbose:
+		return utils.FormatVerbose(worktrees)
+	default:
+		return utils.FormatBasic(worktrees)
+	}
+}
+
 // applyFilters applies name and branch filters to worktrees
 func (lc *ListCommand) applyFilters(worktrees []models.Worktree) []models.Worktree {
 	result := worktrees
@@ -79,6 +144,18 @@ func (lc *ListCommand) applyFilters(worktrees []models.Worktree) []models.Worktr
 	return result
 }
 
+// checkSubmodules counts the uninitialized submodules of each worktree
+func (lc *ListCommand) checkSubmodules(worktrees []models.Worktree) {
+	for i := range worktrees {
+		if worktrees[i].IsPrunable() {
+			continue
+		}
+		if count, err := lc.gitService.CountUninitializedSubmodules(worktrees[i].Path); err == nil {
+			worktrees[i].UninitializedSubmodules = count
+		}
+	}
+}
+
 // containsSubstring checks if a string contains a substring (case-insensitive)
 func containsSubstring(str, substr string) bool {
 	strLower := strings.ToLower(str)
@@ -106,14 +183,20 @@ func (lc *ListCommand) SetJSONOutput(jsonOutput bool) {
 	lc.jsonOutput = jsonOutput
 }
 
+// SetAllRepos lists the worktrees of every registered repository
+func (lc *ListCommand) SetAllRepos(allRepos bool) {
+	lc.allRepos = allRepos
+}
+
 // RunListCommand is the entry point for the list command
-func RunListCommand(repoPath, gitPath string, verbose, jsonOutput bool, filter, branch string) error {
+func RunListCommand(repoPath, gitPath string, verbose, jsonOutput, allRepos bool, filter, branch string) error {
 	gitService := services.NewGitService(gitPath)
 	listCmd := NewListCommand(gitService)
 	listCmd.SetVerbose(verbose)
 	listCmd.SetJSONOutput(jsonOutput)
 	listCmd.SetFilter(filter)
 	listCmd.SetBranch(branch)
+	listCmd.SetAllRepos(allRepos)
 
 	return listCmd.Execute(repoPath)
 }
@@ -0,0 +1,81 @@
+// Lock and unlock command implementation
+package commands
+
+import (
+	"fmt"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// LockCommand handles the 'wt lock' and 'wt unlock' commands
+type LockCommand struct {
+	gitService *services.GitService
+	worktree   string // Path, directory name or branch of the worktree
+	reason     string
+	unlock     bool
+}
+
+// NewLockCommand creates a new LockCommand instance
+func NewLockCommand(gitService *services.GitService) *LockCommand {
+	return &LockCommand{
+		gitService: gitService,
+	}
+}
+
+// SetWorktree sets the worktree to lock or unlock, by path, directory name or branch
+func (lc *LockCommand) SetWorktree(worktree string) {
+	lc.worktree = worktree
+}
+
+// SetReason sets why the worktree is locked
+func (lc *LockCommand) SetReason(reason string) {
+	lc.reason = reason
+}
+
+// SetUnlock sets whether to unlock instead of lock
+func (lc *LockCommand) SetUnlock(unlock bool) {
+	lc.unlock = unlock
+}
+
+// Execute locks or unlocks the worktree
+func (lc *LockCommand) Execute(repoPath string) error {
+	worktrees, err := lc.gitService.GetWorktrees(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	wt, err := FindWorktree(worktrees, lc.worktree)
+	if err != nil {
+		return err
+	}
+
+	if lc.unlock {
+		if !wt.IsLocked {
+			return fmt.Errorf("worktree %s is not locked", wt.Path)
+		}
+		if err := lc.gitService.UnlockWorktree(repoPath, wt.Path); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Unlocked %s\n", wt.Path)
+		return nil
+	}
+
+	if wt.IsLocked {
+		return fmt.Errorf("worktree %s is already %s", wt.Path, wt.DescribeLock())
+	}
+	if err := lc.gitService.LockWorktree(repoPath, wt.Path, lc.reason); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Locked %s\n", wt.Path)
+	return nil
+}
+
+// RunLockCommand is the entry point for the lock and unlock commands
+func RunLockCommand(repoPath, gitPath, worktree, reason string, unlock bool) error {
+	gitService := services.NewGitService(gitPath)
+	lockCmd := NewLockCommand(gitService)
+	lockCmd.SetWorktree(worktree)
+	lockCmd.SetReason(reason)
+	lockCmd.SetUnlock(unlock)
+
+	return lockCmd.Execute(repoPath)
+}
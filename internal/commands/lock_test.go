@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+func TestLockUnlockAndRemove(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+	wtPath := addTestWorktree(t, gs, repoDir, "feature/usb")
+
+	lc := NewLockCommand(gs)
+	lc.SetWorktree("feature/usb")
+	lc.SetReason("on USB disk")
+	if _, err := captureStdout(t, func() error { return lc.Execute(repoDir) }); err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+
+	worktrees, err := gs.GetWorktrees(repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := FindWorktree(worktrees, "feature-usb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wt.IsLocked || wt.LockReason != "on USB disk" {
+		t.Errorf("worktree lock = %v %q, want locked with reason", wt.IsLocked, wt.LockReason)
+	}
+
+	rc := NewRemoveCommand(gs)
+	rc.SetWorktree(wtPath)
+	if err := rc.Execute(repoDir); err == nil || !strings.Contains(err.Error(), "locked: on USB disk") {
+		t.Errorf("expected remove to be refused with the lock reason, got %v", err)
+	}
+	if _, err := os.Stat(wtPath); err != nil {
+		t.Fatalf("locked worktree was removed: %v", err)
+	}
+
+	lc = NewLockCommand(gs)
+	lc.SetWorktree("feature-usb")
+	if err := lc.Execute(repoDir); err == nil || !strings.Contains(err.Error(), "already locked") {
+		t.Errorf("expected locking twice to fail, got %v", err)
+	}
+
+	lc = NewLockCommand(gs)
+	lc.SetWorktree("feature-usb")
+	lc.SetUnlock(true)
+	if _, err := captureStdout(t, func() error { return lc.Execute(repoDir) }); err != nil {
+		t.Fatalf("unlock failed: %v", err)
+	}
+
+	rc = NewRemoveCommand(gs)
+	rc.SetWorktree("feature-usb")
+	if _, err := captureStdout(t, func() error { return rc.Execute(repoDir) }); err != nil {
+		t.Fatalf("remove after unlock failed: %v", err)
+	}
+	if _, err := os.Stat(wtPath); err == nil {
+		t.Errorf("worktree %s was not removed", wtPath)
+	}
+}
@@ -0,0 +1,174 @@
+// Move command implementation
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/services"
+	"github.com/smoerfugl/wt/internal/utils"
+)
+
+// MoveCommand handles the 'wt move' command
+type MoveCommand struct {
+	gitService *services.GitService
+	worktree   string // Path, directory name or branch of the worktree to move
+	newName    string // New branch name; the directory is named after it
+}
+
+// NewMoveCommand creates a new MoveCommand instance
+func NewMoveCommand(gitService *services.GitService) *MoveCommand {
+	return &MoveCommand{
+		gitService: gitService,
+	}
+}
+
+// SetWorktree sets the worktree to move, by path, directory name or branch
+func (mc *MoveCommand) SetWorktree(worktree string) {
+	mc.worktree = worktree
+}
+
+// SetNewName sets the new branch name
+func (mc *MoveCommand) SetNewName(newName string) {
+	mc.newName = newName
+}
+
+// Execute renames the worktree's branch and moves its directory to match.
+// If a step fails, the steps before it are undone.
+func (mc *MoveCommand) Execute(repoPath string) error {
+	// Work from the main worktree: the worktree being moved may be the current directory
+	mainPath, err := mc.gitService.GetMainWorktree(repoPath)
+	if err != nil {
+		return err
+	}
+	worktrees, err := mc.gitService.GetWorktrees(mainPath)
+	if err != nil {
+		return fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	wt, err := FindWorktree(worktrees, mc.worktree)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case samePath(wt.Path, mainPath):
+		return fmt.Errorf("the main worktree cannot be moved")
+	case wt.IsLocked:
+		return fmt.Errorf("worktree %s is %s; run 'wt unlock %s' first", wt.Path, wt.DescribeLock(), wt.Name)
+	case wt.IsPrunable():
+		return fmt.Errorf("worktree %s is missing (%s); run 'wt prune' first", wt.Path, wt.PrunableReason)
+	}
+
+	renameBranch := wt.Branch != "" && wt.Branch != mc.newName
+	if renameBranch {
+		if err := mc.gitService.CheckBranchName(mainPath, mc.newName); err != nil {
+			return err
+		}
+		if mc.gitService.BranchExists(mainPath, mc.newName) {
+			return fmt.Errorf("branch %s already exists", mc.newName)
+		}
+	}
+
+	newPath := filepath.Join(mc.gitService.GetWorktreesDir(mainPath), utils.Slug(mc.newName))
+	movePath := !samePath(newPath, wt.Path)
+	if movePath {
+		if _, err := os.Lstat(newPath); err == nil {
+			return fmt.Errorf("%s already exists", newPath)
+		}
+	}
+	if !renameBranch && !movePath {
+		fmt.Println("Nothing to do")
+		return nil
+	}
+
+	tx := &transaction{}
+	if err := mc.move(mainPath, wt.Path, wt.Branch, newPath, renameBranch, movePath, tx); err != nil {
+		if rbErr := tx.rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback incomplete: %v)", err, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// move performs the rename and the move, recording how to undo each in tx
+func (mc *MoveCommand) move(mainPath, oldPath, oldBranch, newPath string, renameBranch, movePath bool, tx *transaction) error {
+	if renameBranch {
+		upstream := mc.gitService.GetUpstream(mainPath, oldBranch)
+		if err := mc.gitService.RenameBranch(mainPath, oldBranch, mc.newName); err != nil {
+			return err
+		}
+		tx.record("rename branch "+mc.newName+" back to "+oldBranch, func() error {
+			return mc.gitService.RenameBranch(mainPath, mc.newName, oldBranch)
+		})
+		fmt.Printf("✓ Renamed branch %s to %s\n", oldBranch, mc.newName)
+		mc.updateUpstream(mainPath, oldBranch, upstream, tx)
+	}
+
+	if movePath {
+		if err := mc.gitService.MoveWorktree(mainPath, oldPath, newPath); err != nil {
+			return err
+		}
+		tx.record("move worktree back to "+oldPath, func() error {
+			return mc.gitService.MoveWorktree(mainPath, newPath, oldPath)
+		})
+		fmt.Printf("✓ Moved worktree to %s\n", newPath)
+
+		if cwd, err := os.Getwd(); err == nil && isWithin(cwd, oldPath) {
+			fmt.Printf("Your shell is still in the old directory; run: cd %s\n", newPath)
+		}
+	}
+	return nil
+}
+
+// updateUpstream points the renamed branch at the remote branch of the same name.
+// git keeps the old upstream on rename; if it tracked the old name, pushing would
+// go to the wrong branch, so it is switched to <remote>/<new-name> or removed.
+// The old upstream is recorded in tx so that a rollback restores it.
+func (mc *MoveCommand) updateUpstream(mainPath, oldBranch, upstream string, tx *transaction) {
+	if upstream == "" {
+		return
+	}
+	remote, remoteBranch, ok := strings.Cut(upstream, "/")
+	if !ok || remoteBranch != oldBranch {
+		// The branch tracks something else, e.g. origin/main; leave it alone
+		return
+	}
+
+	// Restore the config directly: the old remote branch may no longer exist,
+	// which 'git branch --set-upstream-to' would refuse
+	remoteKey, mergeKey := "branch."+mc.newName+".remote", "branch."+mc.newName+".merge"
+	oldRemote := mc.gitService.GetConfigValue(mainPath, remoteKey)
+	oldMerge := mc.gitService.GetConfigValue(mainPath, mergeKey)
+	tx.record("restore upstream "+upstream, func() error {
+		if err := mc.gitService.SetConfigValue(mainPath, remoteKey, oldRemote); err != nil {
+			return err
+		}
+		return mc.gitService.SetConfigValue(mainPath, mergeKey, oldMerge)
+	})
+
+	newUpstream := remote + "/" + mc.newName
+	if _, err := mc.gitService.ResolveRef(mainPath, newUpstream); err == nil {
+		if err := mc.gitService.SetUpstream(mainPath, mc.newName, newUpstream); err == nil {
+			fmt.Printf("✓ Upstream set to %s\n", newUpstream)
+			return
+		}
+	}
+	if err := mc.gitService.UnsetUpstream(mainPath, mc.newName); err != nil {
+		fmt.Printf("! Could not remove upstream %s: %v\n", upstream, err)
+		return
+	}
+	fmt.Printf("! Removed upstream %s; publish the new name with 'git push -u %s %s'\n", upstream, remote, mc.newName)
+}
+
+// RunMoveCommand is the entry point for the move command
+func RunMoveCommand(repoPath, gitPath, worktree, newName string) error {
+	gitService := services.NewGitService(gitPath)
+	moveCmd := NewMoveCommand(gitService)
+	moveCmd.SetWorktree(worktree)
+	moveCmd.SetNewName(newName)
+
+	return moveCmd.Execute(repoPath)
+}
@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// addTestWorktree creates branch in a new worktree with wt add and returns its path
+func addTestWorktree(t *testing.T, gs *services.GitService, repoDir, branch string) string {
+	t.Helper()
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName(branch)
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add %s failed: %v", branch, err)
+	}
+	return ac.worktreePath
+}
+
+func TestMoveRenamesBranchAndDirectory(t *testing.T) {
+	repoDir := newTestRepo(t)
+	addTestRemote(t, repoDir, "origin")
+	gs := services.NewGitService("")
+	oldPath := addTestWorktree(t, gs, repoDir, "feature/login")
+	runTestGit(t, oldPath, "push", "-q", "-u", "origin", "feature/login")
+
+	mc := NewMoveCommand(gs)
+	mc.SetWorktree("feature-login")
+	mc.SetNewName("feature/sign-in")
+	if _, err := captureStdout(t, func() error { return mc.Execute(repoDir) }); err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+
+	newPath := filepath.Join(gs.GetWorktreesDir(repoDir), "feature-sign-in")
+	if _, err := os.Stat(oldPath); err == nil {
+		t.Errorf("old directory %s still exists", oldPath)
+	}
+	if branch := runTestGit(t, newPath, "symbolic-ref", "--short", "HEAD"); branch != "feature/sign-in" {
+		t.Errorf("branch = %q, want feature/sign-in", branch)
+	}
+	if gs.BranchExists(repoDir, "feature/login") {
+		t.Errorf("old branch still exists")
+	}
+	// origin/feature/sign-in does not exist, so the stale upstream is removed
+	if upstream := gs.GetUpstream(repoDir, "feature/sign-in"); upstream != "" {
+		t.Errorf("upstream = %q, want none", upstream)
+	}
+}
+
+func TestMoveSetsMatchingUpstream(t *testing.T) {
+	repoDir := newTestRepo(t)
+	addTestRemote(t, repoDir, "origin")
+	gs := services.NewGitService("")
+	wtPath := addTestWorktree(t, gs, repoDir, "old-name")
+	runTestGit(t, wtPath, "push", "-q", "-u", "origin", "old-name")
+	runTestGit(t, wtPath, "push", "-q", "origin", "old-name:new-name")
+
+	mc := NewMoveCommand(gs)
+	mc.SetWorktree(wtPath)
+	mc.SetNewName("new-name")
+	if _, err := captureStdout(t, func() error { return mc.Execute(repoDir) }); err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	if upstream := gs.GetUpstream(repoDir, "new-name"); upstream != "origin/new-name" {
+		t.Errorf("upstream = %q, want origin/new-name", upstream)
+	}
+}
+
+func TestMoveRefuses(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+	lockedPath := addTestWorktree(t, gs, repoDir, "locked")
+	runTestGit(t, repoDir, "worktree", "lock", lockedPath)
+	addTestWorktree(t, gs, repoDir, "one")
+	addTestWorktree(t, gs, repoDir, "two")
+
+	tests := []struct {
+		worktree, newName, wantErr string
+	}{
+		{"locked", "unlocked", "is locked"},
+		{"one", "two", "already exists"},
+		{"one", "bad name", "invalid branch name"},
+		{"missing", "x", "no worktree named"},
+		{repoDir, "x", "main worktree"},
+	}
+	for _, tt := range tests {
+		mc := NewMoveCommand(gs)
+		mc.SetWorktree(tt.worktree)
+		mc.SetNewName(tt.newName)
+		if err := mc.Execute(repoDir); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("move %s %s: error = %v, want %q", tt.worktree, tt.newName, err, tt.wantErr)
+		}
+	}
+
+	// The target directory exists but the branch name is free
+	if err := os.MkdirAll(filepath.Join(gs.GetWorktreesDir(repoDir), "three"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mc := NewMoveCommand(gs)
+	mc.SetWorktree("one")
+	mc.SetNewName("three")
+	if err := mc.Execute(repoDir); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected existing target to be refused, got %v", err)
+	}
+	if !gs.BranchExists(repoDir, "one") || gs.BranchExists(repoDir, "three") {
+		t.Errorf("branch was renamed although the move was refused")
+	}
+}
+
+func TestMoveRollbackRestoresUpstream(t *testing.T) {
+	repoDir := newTestRepo(t)
+	addTestRemote(t, repoDir, "origin")
+	gs := services.NewGitService("")
+	oldPath := addTestWorktree(t, gs, repoDir, "old-name")
+	runTestGit(t, oldPath, "push", "-q", "-u", "origin", "old-name")
+
+	mc := NewMoveCommand(gs)
+	mc.SetNewName("new-name")
+	newPath := filepath.Join(gs.GetWorktreesDir(repoDir), "new-name")
+	tx := &transaction{out: io.Discard}
+	if _, err := captureStdout(t, func() error {
+		return mc.move(repoDir, oldPath, "old-name", newPath, true, true, tx)
+	}); err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	if err := tx.rollback(); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+
+	if !exists(oldPath) || exists(newPath) {
+		t.Errorf("worktree was not moved back to %s", oldPath)
+	}
+	if upstream := gs.GetUpstream(repoDir, "old-name"); upstream != "origin/old-name" {
+		t.Errorf("upstream after rollback = %q, want origin/old-name", upstream)
+	}
+}
+
+func TestMoveWorktreeWithSubmodules(t *testing.T) {
+	repoDir := newRepoWithSubmodule(t)
+	gs := services.NewGitService("")
+	if !gs.HasCapability(models.CapWorktreeRepair) {
+		t.Skip("git worktree repair not available")
+	}
+	oldPath := addTestWorktree(t, gs, repoDir, "with-subs")
+
+	mc := NewMoveCommand(gs)
+	mc.SetWorktree("with-subs")
+	mc.SetNewName("renamed")
+	if out, err := captureStdout(t, func() error { return mc.Execute(repoDir) }); err != nil {
+		t.Fatalf("move failed: %v\n%s", err, out)
+	}
+
+	newPath := filepath.Join(gs.GetWorktreesDir(repoDir), "renamed")
+	if exists(oldPath) || !exists(filepath.Join(newPath, "libs", "sub", "README.md")) {
+		t.Fatalf("worktree with submodules was not moved to %s", newPath)
+	}
+	if status := runTestGit(t, newPath, "status", "--short"); status != "" {
+		t.Errorf("moved worktree is not clean: %s", status)
+	}
+	if branch := runTestGit(t, newPath, "symbolic-ref", "--short", "HEAD"); branch != "renamed" {
+		t.Errorf("branch = %q, want renamed", branch)
+	}
+}
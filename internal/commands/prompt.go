@@ -0,0 +1,54 @@
+// Interactive prompts shared by commands
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// promptYesNo asks a yes/no question on out and reads the answer from input.
+// An empty answer counts as yes. End of input returns onEOF, which callers set
+// to the safe answer for when nobody is there to ask.
+func promptYesNo(out io.Writer, input io.Reader, prompt string, onEOF bool) bool {
+	fmt.Fprint(out, prompt)
+	answer, err := bufio.NewReader(input).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(out)
+		return onEOF
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// promptChoice shows a numbered list of options on out and returns the one selected
+// by number or name. Entering 'q' or reaching end of input cancels.
+func promptChoice(out io.Writer, input io.Reader, prompt string, options []string) (string, error) {
+	fmt.Fprintln(out, prompt)
+	for i, option := range options {
+		fmt.Fprintf(out, "%d: %s\n", i+1, option)
+	}
+	fmt.Fprint(out, "\nEnter number to select (or 'q' to quit): ")
+
+	answer, err := bufio.NewReader(input).ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if err != nil && answer == "" {
+		fmt.Fprintln(out)
+		return "", fmt.Errorf("no selection made")
+	}
+	if answer == "q" || answer == "quit" {
+		return "", fmt.Errorf("cancelled")
+	}
+
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1], nil
+	}
+	for _, option := range options {
+		if option == answer {
+			return option, nil
+		}
+	}
+	return "", fmt.Errorf("invalid selection")
+}
@@ -0,0 +1,184 @@
+// Prune command implementation
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// PruneCommand handles the 'wt prune' command
+type PruneCommand struct {
+	gitService *services.GitService
+	dryRun     bool
+	expire     string
+	repair     bool
+	input      io.Reader // Source of answers to the repair prompt; nil prunes without asking
+	out        io.Writer // Where progress is reported; nil is stdout
+
+	pruned   []models.Worktree // Pruned, or would be with dryRun
+	repaired []string          // New paths of the moved worktrees that were repaired
+}
+
+// movedWorktree is a prunable worktree whose directory was found at a new location
+type movedWorktree struct {
+	worktree models.Worktree
+	newPath  string
+}
+
+// NewPruneCommand creates a new PruneCommand instance
+func NewPruneCommand(gitService *services.GitService) *PruneCommand {
+	return &PruneCommand{
+		gitService: gitService,
+		input:      os.Stdin,
+	}
+}
+
+// SetOutput sets where progress is reported
+func (pc *PruneCommand) SetOutput(out io.Writer) {
+	pc.out = out
+}
+
+// output returns where progress is reported: stdout unless SetOutput was called
+func (pc *PruneCommand) output() io.Writer {
+	if pc.out == nil {
+		return os.Stdout
+	}
+	return pc.out
+}
+
+// SetInput sets where the answer to the repair prompt is read from; nil prunes
+// moved worktrees without asking unless SetRepair is set
+func (pc *PruneCommand) SetInput(input io.Reader) {
+	pc.input = input
+}
+
+// Pruned returns the worktrees that Execute pruned, or would have with dry run
+func (pc *PruneCommand) Pruned() []models.Worktree {
+	return pc.pruned
+}
+
+// Repaired returns the new paths of the moved worktrees that Execute repaired
+func (pc *PruneCommand) Repaired() []string {
+	return pc.repaired
+}
+
+// SetDryRun sets whether to only report what would be pruned
+func (pc *PruneCommand) SetDryRun(dryRun bool) {
+	pc.dryRun = dryRun
+}
+
+// SetExpire only prunes worktrees missing for longer than expire (e.g. "2.weeks.ago")
+func (pc *PruneCommand) SetExpire(expire string) {
+	pc.expire = expire
+}
+
+// SetRepair repairs moved worktrees without asking first
+func (pc *PruneCommand) SetRepair(repair bool) {
+	pc.repair = repair
+}
+
+// Execute runs the prune command
+func (pc *PruneCommand) Execute(repoPath string) error {
+	worktrees, err := pc.gitService.GetWorktreesWithExpire(repoPath, pc.expire)
+	if err != nil {
+		return fmt.Errorf("failed to get worktrees: %w", err)
+	}
+
+	// Moved worktrees can only be repaired by git 2.30 and newer; older git prunes them
+	canRepair := pc.gitService.HasCapability(models.CapWorktreeRepair)
+
+	var prunable, locked []models.Worktree
+	var moved []movedWorktree
+	for _, wt := range worktrees {
+		if !wt.IsPrunable() {
+			continue
+		}
+		if wt.IsLocked {
+			// git never prunes locked worktrees
+			locked = append(locked, wt)
+			continue
+		}
+		if canRepair {
+			newPath, err := pc.gitService.FindMovedWorktree(repoPath, wt.Path, pc.searchRoots(repoPath, wt.Path))
+			if err == nil && newPath != "" {
+				moved = append(moved, movedWorktree{worktree: wt, newPath: newPath})
+				continue
+			}
+		}
+		prunable = append(prunable, wt)
+	}
+
+	for _, wt := range locked {
+		fmt.Fprintf(pc.output(), "Keeping %s (%s): %s\n", wt.Path, wt.DescribeLock(), wt.PrunableReason)
+	}
+	if len(prunable) == 0 && len(moved) == 0 {
+		fmt.Fprintln(pc.output(), "Nothing to prune")
+		return nil
+	}
+
+	if len(moved) > 0 {
+		fmt.Fprintln(pc.output(), "Worktrees that were moved by hand:")
+		for _, m := range moved {
+			fmt.Fprintf(pc.output(), "  %s -> %s\n", m.worktree.Path, m.newPath)
+		}
+		if pc.dryRun {
+			fmt.Fprintln(pc.output(), "Would repair them with 'git worktree repair'")
+		} else if pc.repair || (pc.input != nil && promptYesNo(pc.output(), pc.input, "Repair them instead of pruning? [Y/n] ", true)) {
+			for _, m := range moved {
+				if err := pc.gitService.RepairWorktree(repoPath, m.newPath); err != nil {
+					return err
+				}
+				pc.repaired = append(pc.repaired, m.newPath)
+				fmt.Fprintf(pc.output(), "✓ Repaired %s\n", m.newPath)
+			}
+		} else {
+			// The user declined; git will prune them along with the others
+			for _, m := range moved {
+				prunable = append(prunable, m.worktree)
+			}
+		}
+	}
+
+	verb := "Pruning"
+	if pc.dryRun {
+		verb = "Would prune"
+	}
+	for _, wt := range prunable {
+		branch := wt.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+		fmt.Fprintf(pc.output(), "%s %s (%s): %s\n", verb, wt.Path, branch, wt.PrunableReason)
+	}
+	pc.pruned = prunable
+
+	if pc.dryRun || len(prunable) == 0 {
+		return nil
+	}
+	return pc.gitService.PruneWorktrees(repoPath, pc.expire)
+}
+
+// searchRoots returns the directories to look in for a worktree moved away from missingPath
+func (pc *PruneCommand) searchRoots(repoPath, missingPath string) []string {
+	roots := []string{filepath.Dir(missingPath), pc.gitService.GetWorktreesDir(repoPath)}
+	if parent := filepath.Dir(repoPath); parent != roots[0] {
+		roots = append(roots, parent)
+	}
+	return roots
+}
+
+// RunPruneCommand is the entry point for the prune command
+func RunPruneCommand(repoPath, gitPath string, dryRun bool, expire string, repair bool) error {
+	gitService := services.NewGitService(gitPath)
+	pruneCmd := NewPruneCommand(gitService)
+	pruneCmd.SetDryRun(dryRun)
+	pruneCmd.SetExpire(expire)
+	pruneCmd.SetRepair(repair)
+
+	return pruneCmd.Execute(repoPath)
+}
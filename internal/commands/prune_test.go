@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+func TestPruneDryRunAndRepair(t *testing.T) {
+	repoDir := newTestRepo(t)
+	base := filepath.Dir(repoDir)
+	movedFrom := filepath.Join(base, "moved")
+	movedTo := filepath.Join(base, "moved-elsewhere")
+	deleted := filepath.Join(base, "deleted")
+	runTestGit(t, repoDir, "worktree", "add", "-b", "moved", movedFrom)
+	runTestGit(t, repoDir, "worktree", "add", "-b", "deleted", deleted)
+	if err := os.Rename(movedFrom, movedTo); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := os.RemoveAll(deleted); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	gs := services.NewGitService("")
+	pc := NewPruneCommand(gs)
+	pc.SetDryRun(true)
+	out, err := captureStdout(t, func() error { return pc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if !strings.Contains(out, "Would prune "+deleted) || !strings.Contains(out, "non-existent location") {
+		t.Errorf("dry run output missing prunable worktree and reason:\n%s", out)
+	}
+	if !strings.Contains(out, movedFrom+" -> "+movedTo) {
+		t.Errorf("dry run output missing moved worktree:\n%s", out)
+	}
+	if list := runTestGit(t, repoDir, "worktree", "list"); !strings.Contains(list, deleted) {
+		t.Fatalf("dry run pruned worktrees:\n%s", list)
+	}
+
+	pc = NewPruneCommand(gs)
+	pc.SetRepair(true)
+	if _, err := captureStdout(t, func() error { return pc.Execute(repoDir) }); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	list := runTestGit(t, repoDir, "worktree", "list", "--porcelain")
+	if strings.Contains(list, deleted) {
+		t.Errorf("deleted worktree was not pruned:\n%s", list)
+	}
+	if !strings.Contains(list, movedTo) || strings.Contains(list, "prunable") {
+		t.Errorf("moved worktree was not repaired:\n%s", list)
+	}
+}
+
+func TestPruneKeepsLockedWorktrees(t *testing.T) {
+	repoDir := newTestRepo(t)
+	base := filepath.Dir(repoDir)
+	locked := filepath.Join(base, "locked")
+	runTestGit(t, repoDir, "worktree", "add", "-b", "locked", locked)
+	runTestGit(t, repoDir, "worktree", "lock", "--reason", "on a USB disk", locked)
+	if err := os.RemoveAll(locked); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	pc := NewPruneCommand(services.NewGitService(""))
+	out, err := captureStdout(t, func() error { return pc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	// Depending on the git version the worktree is reported as kept or not at all
+	if strings.Contains(out, "Pruning") {
+		t.Errorf("locked worktree reported as pruned:\n%s", out)
+	}
+	if len(pc.Pruned()) != 0 {
+		t.Errorf("Pruned() = %v, want none", pc.Pruned())
+	}
+	if list := runTestGit(t, repoDir, "worktree", "list"); !strings.Contains(list, locked) {
+		t.Errorf("locked worktree was pruned:\n%s", list)
+	}
+}
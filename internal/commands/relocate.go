@@ -0,0 +1,221 @@
+// Relocate command implementation
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// RelocateCommand handles the 'wt relocate' command
+type RelocateCommand struct {
+	gitService *services.GitService
+	newRoot    string
+}
+
+// NewRelocateCommand creates a new RelocateCommand instance
+func NewRelocateCommand(gitService *services.GitService) *RelocateCommand {
+	return &RelocateCommand{
+		gitService: gitService,
+	}
+}
+
+// SetNewRoot sets the directory the worktrees are moved to
+func (rc *RelocateCommand) SetNewRoot(newRoot string) {
+	rc.newRoot = newRoot
+}
+
+// Execute moves every worktree under the worktrees directory to the new root,
+// keeping their layout below it, and makes the new root the worktrees directory.
+// The planned moves are journaled first, so running the command again after an
+// interruption finishes the relocation.
+func (rc *RelocateCommand) Execute(repoPath string) error {
+	newRoot, err := filepath.Abs(rc.newRoot)
+	if err != nil {
+		return err
+	}
+	// Work from the main worktree: the worktree being moved may be the current directory
+	mainPath, err := rc.gitService.GetMainWorktree(repoPath)
+	if err != nil {
+		return err
+	}
+
+	relocation, err := rc.gitService.LoadRelocation(mainPath)
+	if err != nil {
+		return err
+	}
+	if relocation != nil {
+		if !samePath(relocation.To, newRoot) {
+			return fmt.Errorf("a relocation from %s to %s was interrupted; run 'wt relocate %s' to finish it first",
+				relocation.From, relocation.To, relocation.To)
+		}
+		fmt.Printf("Resuming relocation from %s to %s\n", relocation.From, relocation.To)
+	} else {
+		if relocation, err = rc.plan(mainPath, newRoot); err != nil || relocation == nil {
+			return err
+		}
+		if err := rc.gitService.SaveRelocation(mainPath, relocation); err != nil {
+			return err
+		}
+		fmt.Printf("Relocating %d worktree(s) from %s to %s\n", len(relocation.Moves), relocation.From, relocation.To)
+	}
+
+	worktrees, err := rc.gitService.GetWorktrees(mainPath)
+	if err != nil {
+		return fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	for _, m := range relocation.Moves {
+		if err := rc.relocate(mainPath, m, worktrees); err != nil {
+			return fmt.Errorf("%w\nRun 'wt relocate %s' again to resume", err, relocation.To)
+		}
+	}
+
+	// git worktree move keeps the links up to date, but check them all once more
+	if rc.gitService.HasCapability(models.CapWorktreeRepair) {
+		for _, m := range relocation.Moves {
+			if err := rc.gitService.RepairWorktree(mainPath, m.To); err != nil {
+				return fmt.Errorf("%w\nRun 'wt relocate %s' again to resume", err, relocation.To)
+			}
+		}
+	}
+
+	if err := rc.gitService.SetConfigValue(mainPath, "wt.root", relocation.To); err != nil {
+		return err
+	}
+	removeEmptyDirs(relocation.From)
+	if err := rc.gitService.ClearRelocation(mainPath); err != nil {
+		return err
+	}
+	fmt.Printf("✓ New worktrees will be created in %s\n", relocation.To)
+
+	if cwd, err := os.Getwd(); err == nil && isWithin(cwd, relocation.From) {
+		fmt.Printf("Your shell is still in the old directory; run: cd %s\n", relocation.To)
+	}
+	return nil
+}
+
+// plan works out the moves of a new relocation, or returns nil if there is nothing
+// to do. It fails before anything is moved if a worktree cannot be relocated.
+func (rc *RelocateCommand) plan(mainPath, newRoot string) (*models.Relocation, error) {
+	oldRoot := rc.gitService.GetWorktreesDir(mainPath)
+	switch {
+	case samePath(oldRoot, newRoot):
+		fmt.Printf("Worktrees are already in %s\n", newRoot)
+		return nil, nil
+	case isWithin(newRoot, oldRoot):
+		return nil, fmt.Errorf("cannot relocate %s into itself", oldRoot)
+	}
+
+	worktrees, err := rc.gitService.GetWorktrees(mainPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	relocation := &models.Relocation{From: oldRoot, To: newRoot}
+	var problems []string
+	for _, wt := range worktrees {
+		if samePath(wt.Path, mainPath) || !isWithin(wt.Path, oldRoot) {
+			continue
+		}
+		rel, err := filepath.Rel(oldRoot, wt.Path)
+		if err != nil {
+			return nil, err
+		}
+		target := filepath.Join(newRoot, rel)
+		switch {
+		case wt.IsLocked:
+			problems = append(problems, fmt.Sprintf("%s is %s; run 'wt unlock %s' first", wt.Path, wt.DescribeLock(), wt.Name))
+		case wt.IsPrunable():
+			problems = append(problems, fmt.Sprintf("%s is missing (%s); restore it or run 'wt prune' first", wt.Path, wt.PrunableReason))
+		case pathExists(target):
+			problems = append(problems, fmt.Sprintf("%s already exists", target))
+		}
+		relocation.Moves = append(relocation.Moves, models.RelocationMove{From: wt.Path, To: target})
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("cannot relocate worktrees:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return relocation, nil
+}
+
+// relocate moves a single worktree. A move that was already finished is skipped,
+// and whatever an interrupted attempt left behind is cleaned up.
+func (rc *RelocateCommand) relocate(mainPath string, m models.RelocationMove, worktrees []models.Worktree) error {
+	registeredAt := func(path string) bool {
+		for _, wt := range worktrees {
+			if samePath(wt.Path, path) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case registeredAt(m.To):
+		// Moved already; a copy may have been interrupted before the original was removed
+		if pathExists(m.From) {
+			if err := rc.gitService.ReconnectSubmodules(m.To); err != nil {
+				return err
+			}
+			if err := os.RemoveAll(m.From); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", m.From, err)
+			}
+		}
+		fmt.Printf("✓ %s was already moved\n", m.To)
+		return nil
+	case !registeredAt(m.From):
+		return fmt.Errorf("worktree %s is no longer registered", m.From)
+	}
+
+	// Only this relocation creates the target, so anything there is a partial copy
+	if err := os.RemoveAll(m.To); err != nil {
+		return fmt.Errorf("failed to remove partial copy %s: %w", m.To, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.To), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(m.To), err)
+	}
+
+	moveErr := rc.gitService.MoveWorktree(mainPath, m.From, m.To)
+	if moveErr == nil {
+		fmt.Printf("✓ Moved %s to %s\n", m.From, m.To)
+		return nil
+	}
+
+	// git cannot move worktrees to another file system; copy those instead
+	// and repair the links. Worktrees with submodules were copied already.
+	if !rc.gitService.HasCapability(models.CapWorktreeRepair) || rc.gitService.HasInitializedSubmodules(m.From) {
+		return moveErr
+	}
+	if err := rc.gitService.CopyWorktree(mainPath, m.From, m.To); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Copied %s to %s\n", m.From, m.To)
+	return nil
+}
+
+// removeEmptyDirs removes dir and the directories below it if they hold nothing else
+func removeEmptyDirs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			removeEmptyDirs(filepath.Join(dir, entry.Name()))
+		}
+	}
+	// Fails, as intended, if anything is left
+	os.Remove(dir)
+}
+
+// RunRelocateCommand is the entry point for the relocate command
+func RunRelocateCommand(repoPath, gitPath, newRoot string) error {
+	gitService := services.NewGitService(gitPath)
+	relocateCmd := NewRelocateCommand(gitService)
+	relocateCmd.SetNewRoot(newRoot)
+
+	return relocateCmd.Execute(repoPath)
+}
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+func TestRelocate(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+	oldRoot := gs.GetWorktreesDir(repoDir)
+	addTestWorktree(t, gs, repoDir, "feature/a")
+	nested := filepath.Join(oldRoot, "team", "b")
+	runTestGit(t, repoDir, "worktree", "add", "-q", "-b", "b", nested)
+
+	newRoot := filepath.Join(t.TempDir(), "fast-disk")
+	rc := NewRelocateCommand(gs)
+	rc.SetNewRoot(newRoot)
+	if output, err := captureStdout(t, func() error { return rc.Execute(repoDir) }); err != nil {
+		t.Fatalf("relocate failed: %v\n%s", err, output)
+	}
+
+	for _, rel := range []string{"feature-a", filepath.Join("team", "b")} {
+		if !exists(filepath.Join(newRoot, rel, "README.md")) {
+			t.Errorf("%s was not moved to the new root", rel)
+		}
+	}
+	if exists(oldRoot) {
+		t.Errorf("old root %s was not removed", oldRoot)
+	}
+	if got := gs.GetWorktreesDir(repoDir); got != newRoot {
+		t.Errorf("worktrees dir = %s, want %s", got, newRoot)
+	}
+	if status := runTestGit(t, filepath.Join(newRoot, "team", "b"), "status", "--short"); status != "" {
+		t.Errorf("relocated worktree is not clean: %s", status)
+	}
+	if relocation, err := gs.LoadRelocation(repoDir); err != nil || relocation != nil {
+		t.Errorf("journal left behind: %v, %v", relocation, err)
+	}
+
+	// New worktrees go to the new root
+	if path := addTestWorktree(t, gs, repoDir, "c"); !samePath(filepath.Dir(path), newRoot) {
+		t.Errorf("new worktree created at %s, want it in %s", path, newRoot)
+	}
+}
+
+func TestRelocateResumes(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+	oldRoot := gs.GetWorktreesDir(repoDir)
+	donePath := addTestWorktree(t, gs, repoDir, "done")
+	partialPath := addTestWorktree(t, gs, repoDir, "partial")
+	newRoot := filepath.Join(t.TempDir(), "new")
+
+	// Simulate a relocation interrupted after moving one worktree and while
+	// copying the other
+	relocation := &models.Relocation{From: oldRoot, To: newRoot, Moves: []models.RelocationMove{
+		{From: donePath, To: filepath.Join(newRoot, "done")},
+		{From: partialPath, To: filepath.Join(newRoot, "partial")},
+	}}
+	if err := gs.SaveRelocation(repoDir, relocation); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(newRoot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, repoDir, "worktree", "move", donePath, filepath.Join(newRoot, "done"))
+	if err := os.MkdirAll(filepath.Join(newRoot, "partial"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(newRoot, "partial", "half-copied"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another target is refused while the relocation is unfinished
+	rc := NewRelocateCommand(gs)
+	rc.SetNewRoot(filepath.Join(t.TempDir(), "elsewhere"))
+	if err := rc.Execute(repoDir); err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("expected unfinished relocation to be reported, got %v", err)
+	}
+
+	rc = NewRelocateCommand(gs)
+	rc.SetNewRoot(newRoot)
+	output, err := captureStdout(t, func() error { return rc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("resume failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Resuming") || !strings.Contains(output, "already moved") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+	if exists(filepath.Join(newRoot, "partial", "half-copied")) || !exists(filepath.Join(newRoot, "partial", "README.md")) {
+		t.Errorf("partial copy was not replaced by the worktree")
+	}
+	if exists(partialPath) {
+		t.Errorf("%s was not moved", partialPath)
+	}
+}
+
+func TestRelocateRefuses(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+	lockedPath := addTestWorktree(t, gs, repoDir, "locked")
+	runTestGit(t, repoDir, "worktree", "lock", "--reason", "in use", lockedPath)
+
+	rc := NewRelocateCommand(gs)
+	rc.SetNewRoot(filepath.Join(t.TempDir(), "new"))
+	if err := rc.Execute(repoDir); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Fatalf("expected locked worktree to be refused, got %v", err)
+	}
+	if !exists(lockedPath) {
+		t.Errorf("locked worktree was moved")
+	}
+	if relocation, _ := gs.LoadRelocation(repoDir); relocation != nil {
+		t.Errorf("journal written although nothing can be moved")
+	}
+}
+
+func TestRelocateCopiesWorktreesWithSubmodules(t *testing.T) {
+	repoDir := newRepoWithSubmodule(t)
+	gs := services.NewGitService("")
+	if !gs.HasCapability(models.CapWorktreeRepair) {
+		t.Skip("git worktree repair not available")
+	}
+	// git worktree move refuses worktrees with submodules
+	oldPath := addTestWorktree(t, gs, repoDir, "with-subs")
+
+	newRoot := filepath.Join(t.TempDir(), "new")
+	rc := NewRelocateCommand(gs)
+	rc.SetNewRoot(newRoot)
+	output, err := captureStdout(t, func() error { return rc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("relocate failed: %v\n%s", err, output)
+	}
+	newPath := filepath.Join(newRoot, "with-subs")
+	if exists(oldPath) || !exists(filepath.Join(newPath, "libs", "sub", "README.md")) {
+		t.Fatalf("worktree was not copied:\n%s", output)
+	}
+	if top := runTestGit(t, newPath, "rev-parse", "--show-toplevel"); !samePath(top, newPath) {
+		t.Errorf("relocated worktree resolves to %s", top)
+	}
+	if status := runTestGit(t, newPath, "status", "--short"); status != "" {
+		t.Errorf("relocated worktree is not clean: %s", status)
+	}
+	if list := runTestGit(t, repoDir, "worktree", "list"); strings.Contains(list, "prunable") {
+		t.Errorf("worktree link was not repaired:\n%s", list)
+	}
+}
+
+func TestRelocateKeepsSpecialFiles(t *testing.T) {
+	repoDir := newRepoWithSubmodule(t)
+	gs := services.NewGitService("")
+	if !gs.HasCapability(models.CapWorktreeRepair) {
+		t.Skip("git worktree repair not available")
+	}
+	// Worktrees with submodules are copied, which cannot carry a socket along
+	oldPath := addTestWorktree(t, gs, repoDir, "with-subs")
+	listener, err := net.Listen("unix", filepath.Join(oldPath, "s"))
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+	defer listener.Close()
+
+	newRoot := filepath.Join(t.TempDir(), "new")
+	rc := NewRelocateCommand(gs)
+	rc.SetNewRoot(newRoot)
+	if _, err := captureStdout(t, func() error { return rc.Execute(repoDir) }); err == nil || !strings.Contains(err.Error(), "sockets") {
+		t.Fatalf("expected relocate to refuse a worktree with a socket, got %v", err)
+	}
+	if !exists(filepath.Join(oldPath, "s")) || !exists(filepath.Join(oldPath, "libs", "sub", "README.md")) {
+		t.Errorf("original worktree was removed")
+	}
+	if exists(filepath.Join(newRoot, "with-subs")) {
+		t.Errorf("partial copy was left behind")
+	}
+}
@@ -0,0 +1,162 @@
+// Remove command implementation
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// RemoveCommand handles the 'wt remove' command
+type RemoveCommand struct {
+	gitService *services.GitService
+	worktree   string   // Path, directory name or branch of the worktree to remove
+	repos      []string // Registered repositories or groups to remove the worktree from
+	force      bool
+	out        io.Writer // Where progress is reported; nil is stdout
+}
+
+// NewRemoveCommand creates a new RemoveCommand instance
+func NewRemoveCommand(gitService *services.GitService) *RemoveCommand {
+	return &RemoveCommand{
+		gitService: gitService,
+	}
+}
+
+// SetOutput sets where progress is reported
+func (rc *RemoveCommand) SetOutput(out io.Writer) {
+	rc.out = out
+}
+
+// output returns where progress is reported: stdout unless SetOutput was called
+func (rc *RemoveCommand) output() io.Writer {
+	if rc.out == nil {
+		return os.Stdout
+	}
+	return rc.out
+}
+
+// SetForce removes the worktree even if it has uncommitted changes
+func (rc *RemoveCommand) SetForce(force bool) {
+	rc.force = force
+}
+
+// SetWorktree sets the worktree to remove, by path, directory name or branch
+func (rc *RemoveCommand) SetWorktree(worktree string) {
+	rc.worktree = worktree
+}
+
+// SetRepos removes the worktree from each of these registered repositories or
+// groups of them instead of the current repository
+func (rc *RemoveCommand) SetRepos(repos []string) {
+	rc.repos = repos
+}
+
+// Execute removes the worktree. Locked worktrees are refused with their lock reason.
+func (rc *RemoveCommand) Execute(repoPath string) error {
+	if len(rc.repos) > 0 {
+		return rc.executeRepos()
+	}
+	worktrees, err := rc.gitService.GetWorktrees(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	wt, err := FindWorktree(worktrees, rc.worktree)
+	if err != nil {
+		return err
+	}
+	if wt.IsLocked {
+		return fmt.Errorf("worktree %s is %s; run 'wt unlock %s' first", wt.Path, wt.DescribeLock(), wt.Name)
+	}
+
+	if err := rc.gitService.RemoveWorktree(repoPath, wt.Path, rc.force); err != nil {
+		return err
+	}
+	fmt.Fprintf(rc.output(), "✓ Removed %s\n", wt.Path)
+	return nil
+}
+
+// repoWorktree is a worktree of a registered repository
+type repoWorktree struct {
+	repo     models.RegisteredRepo
+	worktree models.Worktree
+}
+
+// executeRepos removes the worktree from every repository named by rc.repos.
+// Repositories without it are skipped. Nothing is removed if any of the
+// worktrees is locked, has uncommitted changes (unless forced) or is a main worktree.
+func (rc *RemoveCommand) executeRepos() error {
+	repos, err := resolveRepos(rc.gitService, rc.repos)
+	if err != nil {
+		return err
+	}
+
+	var targets []repoWorktree
+	var problems []string
+	for _, repo := range repos {
+		worktrees, err := rc.gitService.GetWorktrees(repo.Path)
+		if err != nil {
+			return fmt.Errorf("%s: failed to get worktrees: %w", repo.Name, err)
+		}
+		wt, err := FindWorktree(worktrees, rc.worktree)
+		if err != nil {
+			fmt.Fprintf(rc.output(), "%s has no worktree %s; skipping\n", repo.Name, rc.worktree)
+			continue
+		}
+		mainPath, err := rc.gitService.GetMainWorktree(repo.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", repo.Name, err)
+		}
+
+		switch {
+		case samePath(wt.Path, mainPath) || wt.IsBare:
+			problems = append(problems, fmt.Sprintf("%s: %s is the main worktree", repo.Name, wt.Path))
+		case wt.IsLocked:
+			problems = append(problems, fmt.Sprintf("%s: %s is %s; run 'wt unlock %s' first", repo.Name, wt.Path, wt.DescribeLock(), wt.Name))
+		case !wt.IsPrunable() && !rc.force:
+			status, err := rc.gitService.GetWorktreeStatus(wt.Path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", repo.Name, err)
+			}
+			if status.IsDirty() {
+				problems = append(problems, fmt.Sprintf("%s: %s has uncommitted changes", repo.Name, wt.Path))
+			}
+		}
+		targets = append(targets, repoWorktree{repo: repo, worktree: wt})
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("nothing was removed:\n  %s", strings.Join(problems, "\n  "))
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no worktree %s in any of the repositories", rc.worktree)
+	}
+
+	failed := 0
+	for _, t := range targets {
+		if err := rc.gitService.RemoveWorktree(t.repo.Path, t.worktree.Path, rc.force); err != nil {
+			fmt.Fprintf(rc.output(), "✗ %s: %v\n", t.repo.Name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(rc.output(), "✓ Removed %s (%s)\n", t.worktree.Path, t.repo.Name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d worktree(s) could not be removed", failed)
+	}
+	return nil
+}
+
+// RunRemoveCommand is the entry point for the remove command
+func RunRemoveCommand(repoPath, gitPath, worktree string, repos []string, force bool) error {
+	gitService := services.NewGitService(gitPath)
+	removeCmd := NewRemoveCommand(gitService)
+	removeCmd.SetWorktree(worktree)
+	removeCmd.SetRepos(repos)
+	removeCmd.SetForce(force)
+
+	return removeCmd.Execute(repoPath)
+}
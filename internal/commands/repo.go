@@ -0,0 +1,345 @@
+// Repo command implementation
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// maxRepoJobs is how many registered repositories are read at the same time
+const maxRepoJobs = 8
+
+// RepoCommand handles the 'wt repo add|remove|list' command
+type RepoCommand struct {
+	gitService *services.GitService
+	action     string // add, remove or list
+	target     string // Repository path to add, or name or path to remove
+	name       string // Name to register the repository as
+}
+
+// NewRepoCommand creates a new RepoCommand instance
+func NewRepoCommand(gitService *services.GitService) *RepoCommand {
+	return &RepoCommand{
+		gitService: gitService,
+	}
+}
+
+// SetAction sets the subcommand to run: add, remove or list
+func (rc *RepoCommand) SetAction(action string) {
+	rc.action = action
+}
+
+// SetTarget sets the repository to add or remove
+func (rc *RepoCommand) SetTarget(target string) {
+	rc.target = target
+}
+
+// SetName sets the name to register the repository as
+func (rc *RepoCommand) SetName(name string) {
+	rc.name = name
+}
+
+// Execute adds to, removes from or lists the repository registry. repoPath is
+// the repository added when no target is given.
+func (rc *RepoCommand) Execute(repoPath string) error {
+	switch rc.action {
+	case "add":
+		return rc.add(repoPath)
+	case "remove":
+		return rc.remove()
+	case "list":
+		return rc.list()
+	default:
+		return fmt.Errorf("unknown repo action %q (want add, remove or list)", rc.action)
+	}
+}
+
+// add registers the repository at rc.target, or repoPath
+func (rc *RepoCommand) add(repoPath string) error {
+	path := repoPath
+	if rc.target != "" {
+		path = rc.target
+	}
+	root, err := repoRoot(rc.gitService, path)
+	if err != nil {
+		return err
+	}
+	name := rc.name
+	if name == "" {
+		name = defaultRepoName(root)
+	}
+	if err := services.CheckRepoName(name); err != nil {
+		return err
+	}
+
+	repos, err := rc.gitService.GetRegisteredRepos()
+	if err != nil {
+		return err
+	}
+	for _, repo := range repos {
+		switch {
+		case samePath(repo.Path, root) && repo.Name == name:
+			fmt.Printf("%s is already registered as %s\n", root, name)
+			return nil
+		case samePath(repo.Path, root):
+			return fmt.Errorf("%s is already registered as %s; run 'wt repo remove %s' first", root, repo.Name, repo.Name)
+		case repo.Name == name:
+			return fmt.Errorf("the name %s is used by %s; choose another with --name", name, repo.Path)
+		}
+	}
+
+	if err := rc.gitService.RegisterRepo(name, root); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Registered %s (%s)\n", name, root)
+	return nil
+}
+
+// remove unregisters the repository named rc.target, or registered at that path
+func (rc *RepoCommand) remove() error {
+	repos, err := rc.gitService.GetRegisteredRepos()
+	if err != nil {
+		return err
+	}
+	repo, err := findRegisteredRepo(repos, rc.target)
+	if err != nil {
+		return err
+	}
+	if err := rc.gitService.UnregisterRepo(repo.Name); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Unregistered %s (%s)\n", repo.Name, repo.Path)
+	return nil
+}
+
+// list prints the registered repositories, flagging those that no longer exist
+func (rc *RepoCommand) list() error {
+	repos, err := rc.gitService.GetRegisteredRepos()
+	if err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		fmt.Println("No repositories registered; add one with 'wt repo add'")
+		return nil
+	}
+
+	width := len("NAME")
+	for _, repo := range repos {
+		width = max(width, len(repo.Name))
+	}
+	fmt.Printf("%-*s  %s\n", width, "NAME", "PATH")
+	for _, repo := range repos {
+		path := repo.Path
+		if _, err := os.Stat(repo.Path); err != nil {
+			path += " (missing)"
+		}
+		fmt.Printf("%-*s  %s\n", width, repo.Name, path)
+	}
+
+	groups, err := rc.gitService.GetRepoGroups()
+	if err != nil {
+		return err
+	}
+	if len(groups) > 0 {
+		fmt.Println()
+		fmt.Println("Groups:")
+		names := make([]string, 0, len(groups))
+		for name := range groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  %s: %s\n", name, strings.Join(groups[name], ", "))
+		}
+	}
+	return nil
+}
+
+// findRegisteredRepo returns the registered repository that arg names or points to
+func findRegisteredRepo(repos []models.RegisteredRepo, arg string) (models.RegisteredRepo, error) {
+	for _, repo := range repos {
+		if repo.Name == arg {
+			return repo, nil
+		}
+	}
+	if abs, err := filepath.Abs(arg); err == nil {
+		for _, repo := range repos {
+			if samePath(repo.Path, abs) {
+				return repo, nil
+			}
+		}
+	}
+	return models.RegisteredRepo{}, fmt.Errorf("no registered repository named %q (see 'wt repo list')", arg)
+}
+
+// resolveRepos expands the names given to --repos, each a registered repository
+// or a wt.group.<name> group of them, into registered repositories. Names may be
+// comma-separated; repositories are returned once, in the order first named. It
+// fails if any repository is unknown or no longer exists.
+func resolveRepos(gs *services.GitService, names []string) ([]models.RegisteredRepo, error) {
+	repos, err := gs.GetRegisteredRepos()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := gs.GetRepoGroups()
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]models.RegisteredRepo{}
+	for _, repo := range repos {
+		byName[repo.Name] = repo
+	}
+
+	var resolved []models.RegisteredRepo
+	seen := map[string]bool{}
+	add := func(name, group string) error {
+		repo, ok := byName[name]
+		switch {
+		case !ok && group != "":
+			return fmt.Errorf("group %s names %s, which is not a registered repository (see 'wt repo list')", group, name)
+		case !ok:
+			return fmt.Errorf("%s is neither a registered repository nor a group (see 'wt repo list')", name)
+		case !pathExists(repo.Path):
+			return fmt.Errorf("registered repository %s no longer exists at %s", name, repo.Path)
+		case !seen[name]:
+			seen[name] = true
+			resolved = append(resolved, repo)
+		}
+		return nil
+	}
+	for _, arg := range names {
+		for _, name := range strings.Split(arg, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			// Repository names take precedence over group names
+			members, isGroup := groups[strings.ToLower(name)]
+			if _, isRepo := byName[name]; isRepo || !isGroup {
+				if err := add(name, ""); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			for _, member := range members {
+				if err := add(member, name); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("no repositories given")
+	}
+	return resolved, nil
+}
+
+// repoRoot returns the path a repository is registered under: its main
+// worktree, or the repository itself if it is bare
+func repoRoot(gs *services.GitService, path string) (string, error) {
+	root, err := gs.GetMainWorktree(path)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", path, err)
+	}
+	return root, nil
+}
+
+// defaultRepoName names a repository after its directory; the bare repository
+// of a hub is named after the hub
+func defaultRepoName(root string) string {
+	if filepath.Base(root) == hubGitDir {
+		return filepath.Base(filepath.Dir(root))
+	}
+	return filepath.Base(root)
+}
+
+// autoRegister adds the repository at repoPath to the registry unless it is
+// already registered, picking a free name, and reports it to out. Registry
+// problems never fail the command that triggered it.
+func autoRegister(gs *services.GitService, repoPath string, out io.Writer) {
+	root, err := repoRoot(gs, repoPath)
+	if err != nil {
+		return
+	}
+	repos, err := gs.GetRegisteredRepos()
+	if err != nil {
+		return
+	}
+	taken := map[string]bool{}
+	for _, repo := range repos {
+		if samePath(repo.Path, root) {
+			return
+		}
+		taken[repo.Name] = true
+	}
+
+	base := defaultRepoName(root)
+	name := base
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	if services.CheckRepoName(name) != nil {
+		return
+	}
+	if err := gs.RegisterRepo(name, root); err == nil {
+		fmt.Fprintf(out, "Registered repository %s (see 'wt repo list')\n", name)
+	}
+}
+
+// repoResult is the outcome of reading one registered repository
+type repoResult[T any] struct {
+	repo  models.RegisteredRepo
+	value T
+	err   error
+}
+
+// forEachRepo calls fn for every registered repository, several at a time, and
+// returns the results in registry order
+func forEachRepo[T any](gs *services.GitService, fn func(repo models.RegisteredRepo) (T, error)) ([]repoResult[T], error) {
+	repos, err := gs.GetRegisteredRepos()
+	if err != nil {
+		return nil, err
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no repositories registered; add them with 'wt repo add'")
+	}
+
+	// Detect the git version once up front rather than in every worker; a
+	// failure shows up again where a capability is checked
+	gs.ParsedGitVersion()
+
+	results := make([]repoResult[T], len(repos))
+	semaphore := make(chan struct{}, maxRepoJobs)
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			value, err := fn(repo)
+			results[i] = repoResult[T]{repo: repo, value: value, err: err}
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// RunRepoCommand is the entry point for the repo command
+func RunRepoCommand(repoPath, gitPath, action, target, name string) error {
+	gitService := services.NewGitService(gitPath)
+	repoCmd := NewRepoCommand(gitService)
+	repoCmd.SetAction(action)
+	repoCmd.SetTarget(target)
+	repoCmd.SetName(name)
+
+	return repoCmd.Execute(repoPath)
+}
@@ -0,0 +1,269 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/services"
+	"github.com/smoerfugl/wt/internal/utils"
+)
+
+func TestRepoAddRemoveList(t *testing.T) {
+	// newTestRepo starts an empty global config, so create both repositories first
+	otherDir := newTestRepo(t)
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	run := func(action, target, name string) (string, error) {
+		rc := NewRepoCommand(gs)
+		rc.SetAction(action)
+		rc.SetTarget(target)
+		rc.SetName(name)
+		return captureStdout(t, func() error { return rc.Execute(repoDir) })
+	}
+
+	if _, err := run("add", "", ""); err != nil {
+		t.Fatalf("repo add failed: %v", err)
+	}
+	if _, err := run("add", repoDir, "other"); err == nil || !strings.Contains(err.Error(), "already registered as repo") {
+		t.Errorf("expected duplicate path to be refused, got %v", err)
+	}
+	if _, err := run("add", otherDir, ""); err == nil || !strings.Contains(err.Error(), "--name") {
+		t.Errorf("expected duplicate name to be refused, got %v", err)
+	}
+	if _, err := run("add", otherDir, "bad name"); err == nil {
+		t.Errorf("expected invalid name to be refused")
+	}
+	if _, err := run("add", t.TempDir(), ""); err == nil || !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("expected non-repository to be refused, got %v", err)
+	}
+	if _, err := run("add", otherDir, "other"); err != nil {
+		t.Fatalf("repo add --name failed: %v", err)
+	}
+
+	output, err := run("list", "", "")
+	if err != nil || !strings.Contains(output, "other  "+otherDir) || !strings.Contains(output, "repo   "+repoDir) {
+		t.Errorf("repo list = %q, %v", output, err)
+	}
+
+	if _, err := run("remove", repoDir, ""); err != nil {
+		t.Fatalf("repo remove by path failed: %v", err)
+	}
+	if _, err := run("remove", "other", ""); err != nil {
+		t.Fatalf("repo remove by name failed: %v", err)
+	}
+	if repos, _ := gs.GetRegisteredRepos(); len(repos) != 0 {
+		t.Errorf("registry = %v, want empty", repos)
+	}
+	if _, err := run("remove", "repo", ""); err == nil || !strings.Contains(err.Error(), "no registered repository") {
+		t.Errorf("expected unknown repository to be reported, got %v", err)
+	}
+}
+
+func TestAddRegistersRepository(t *testing.T) {
+	second := newTestRepo(t)
+	first := newTestRepo(t)
+	gs := services.NewGitService("")
+
+	addTestWorktree(t, gs, first, "one")
+	addTestWorktree(t, gs, first, "two")
+	addTestWorktree(t, gs, second, "three")
+
+	// Both repositories are called repo; the second gets a suffix
+	repos, err := gs.GetRegisteredRepos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 || repos[0].Name != "repo" || repos[1].Name != "repo-2" || !samePath(repos[1].Path, second) {
+		t.Errorf("registry = %+v, want repo and repo-2", repos)
+	}
+}
+
+func TestAllRepos(t *testing.T) {
+	web := newTestRepo(t)
+	api := newTestRepo(t)
+	gs := services.NewGitService("")
+	for name, path := range map[string]string{
+		"api":  api,
+		"web":  web,
+		"gone": filepath.Join(t.TempDir(), "gone"),
+	} {
+		if err := gs.RegisterRepo(name, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirtyPath := addTestWorktree(t, gs, web, "login")
+	if err := os.WriteFile(filepath.Join(dirtyPath, "README.md"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lc := NewListCommand(gs)
+	lc.SetAllRepos(true)
+	output, err := captureStdout(t, func() error { return lc.Execute("") })
+	if err != nil {
+		t.Fatalf("list --all-repos failed: %v", err)
+	}
+	for _, want := range []string{"api (" + api + ")", "web (" + web + ")", "login"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("list output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "gone") {
+		t.Errorf("missing repository listed on stdout:\n%s", output)
+	}
+
+	lc.SetJSONOutput(true)
+	output, err = captureStdout(t, func() error { return lc.Execute("") })
+	if err != nil || !strings.HasPrefix(output, "[") {
+		t.Errorf("list --all-repos -j = %q, %v", output, err)
+	}
+
+	sc := NewStatusCommand(gs)
+	sc.SetAllRepos(true)
+	sc.SetDirtyOnly(true)
+	statuses, err := sc.collectAllRepos()
+	if err != nil {
+		t.Fatalf("status --all-repos failed: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Repo != "web" || statuses[0].Branch != "login" {
+		t.Errorf("dirty worktrees = %+v, want web's login", statuses)
+	}
+	if table := sc.formatStatus(statuses); !strings.HasPrefix(table, "REPO") {
+		t.Errorf("status table has no REPO column:\n%s", table)
+	}
+}
+
+func TestAddAndRemoveAcrossRepos(t *testing.T) {
+	web := newTestRepo(t)
+	api := newTestRepo(t)
+	gs := services.NewGitService("")
+	for name, path := range map[string]string{"api": api, "web": web} {
+		if err := gs.RegisterRepo(name, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runTestGit(t, api, "config", "--global", "wt.group.fullstack", "api,web")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("feature/x")
+	ac.SetRepos([]string{"fullstack"})
+	ac.AddExecCommand(utils.NewCommand("sh", []string{"-c", `echo "$WT_WORKTREE" > where`}))
+	if _, err := captureStdout(t, func() error { return ac.Execute("") }); err != nil {
+		t.Fatalf("add --repos failed: %v", err)
+	}
+	for _, repo := range []string{api, web} {
+		path := filepath.Join(gs.GetWorktreesDir(repo), "feature-x")
+		if got := runTestGit(t, path, "branch", "--show-current"); got != "feature/x" {
+			t.Errorf("%s is on %q, want feature/x", path, got)
+		}
+		// Each repository runs the exec commands in its own worktree
+		if where, _ := os.ReadFile(filepath.Join(path, "where")); strings.TrimSpace(string(where)) != path {
+			t.Errorf("exec command ran in %q, want %s", where, path)
+		}
+	}
+
+	// A failure in one repository rolls back the others
+	runTestGit(t, web, "branch", "taken")
+	ac = NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("taken")
+	ac.SetRepos([]string{"api", "web"})
+	if _, err := captureStdout(t, func() error { return ac.Execute("") }); err == nil || !strings.Contains(err.Error(), "web:") {
+		t.Fatalf("expected add to fail in web, got %v", err)
+	}
+	if gs.BranchExists(api, "taken") || exists(filepath.Join(gs.GetWorktreesDir(api), "taken")) {
+		t.Errorf("worktree added to api was not rolled back")
+	}
+
+	ac = NewAddCommand(gs)
+	ac.SetBranchName("main")
+	ac.SetRepos([]string{"api,nope"})
+	if _, err := captureStdout(t, func() error { return ac.Execute("") }); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("expected unknown repository to be refused, got %v", err)
+	}
+
+	// Options that only make sense for one repository are refused
+	for name, set := range map[string]func(*AddCommand){
+		"--detach":         func(ac *AddCommand) { ac.SetDetach(true) },
+		"--sparse":         func(ac *AddCommand) { ac.SetSparse([]string{"src"}, nil) },
+		"--sparse-profile": func(ac *AddCommand) { ac.SetSparse(nil, []string{"backend"}) },
+	} {
+		ac = NewAddCommand(gs)
+		ac.SetCreateBranch(true)
+		ac.SetBranchName("feature/z")
+		ac.SetRepos([]string{"fullstack"})
+		set(ac)
+		if err := ac.Execute(""); err == nil || !strings.Contains(err.Error(), "--repos cannot be used") {
+			t.Errorf("expected --repos with %s to be refused, got %v", name, err)
+		}
+	}
+	if gs.BranchExists(api, "feature/z") || gs.BranchExists(web, "feature/z") {
+		t.Errorf("a refused add --repos created branches")
+	}
+
+	// Nothing is removed while one of the worktrees has changes
+	apiPath := filepath.Join(gs.GetWorktreesDir(api), "feature-x")
+	webPath := filepath.Join(gs.GetWorktreesDir(web), "feature-x")
+	rc := NewRemoveCommand(gs)
+	rc.SetWorktree("feature/x")
+	rc.SetRepos([]string{"fullstack"})
+	if _, err := captureStdout(t, func() error { return rc.Execute("") }); err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Fatalf("expected dirty worktrees to be refused, got %v", err)
+	}
+	if !exists(apiPath) || !exists(webPath) {
+		t.Fatalf("worktrees were removed despite the refusal")
+	}
+
+	for _, path := range []string{apiPath, webPath} {
+		os.Remove(filepath.Join(path, "where"))
+	}
+	if _, err := captureStdout(t, func() error { return rc.Execute("") }); err != nil {
+		t.Fatalf("remove --repos failed: %v", err)
+	}
+	if exists(apiPath) || exists(webPath) {
+		t.Errorf("worktrees still exist after remove --repos")
+	}
+}
+
+// cancelOnWrite cancels a context when a write contains marker
+type cancelOnWrite struct {
+	marker string
+	cancel context.CancelFunc
+}
+
+func (w *cancelOnWrite) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), w.marker) {
+		w.cancel()
+	}
+	return len(p), nil
+}
+
+func TestAddAcrossReposRollsBackAfterCancel(t *testing.T) {
+	web := newTestRepo(t)
+	api := newTestRepo(t)
+	gs := services.NewGitService("")
+	for name, path := range map[string]string{"api": api, "web": web} {
+		if err := gs.RegisterRepo(name, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Cancel once api is done, so the rollback of api runs with a cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ac := NewAddCommand(gs.WithContext(ctx))
+	ac.SetOutput(&cancelOnWrite{marker: "==> web", cancel: cancel})
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("feature/y")
+	ac.SetRepos([]string{"api", "web"})
+	if err := ac.Execute(""); err == nil || strings.Contains(err.Error(), "rollback incomplete") {
+		t.Fatalf("expected add to fail and roll back, got %v", err)
+	}
+	if gs.BranchExists(api, "feature/y") || exists(filepath.Join(gs.GetWorktreesDir(api), "feature-y")) {
+		t.Errorf("worktree added to api was not rolled back")
+	}
+}
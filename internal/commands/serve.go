@@ -0,0 +1,584 @@
+// Serve command implementation
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+	"github.com/smoerfugl/wt/internal/utils"
+)
+
+// defaultServeInterval is how often 'wt serve' checks the worktrees for changes
+const defaultServeInterval = 2 * time.Second
+
+// notificationQueueSize is how many notifications may wait for a client that
+// is slow to read them before it is disconnected
+const notificationQueueSize = 64
+
+// serveWriteTimeout is how long a client may take to accept a message before
+// the write fails and the client is disconnected
+const serveWriteTimeout = 10 * time.Second
+
+// Notifications sent by 'wt serve'
+const (
+	notifyChanged  = "changed"  // The worktrees were added, removed, moved, locked or moved to another commit
+	notifySwitched = "switched" // A client asked to switch to a worktree
+)
+
+// ServeCommand handles the 'wt serve' command: a JSON-RPC 2.0 server on a unix
+// socket for editor plugins and other tools
+type ServeCommand struct {
+	gitService *services.GitService
+	socket     string
+	interval   time.Duration // How often to check for changes; 0 only reports the server's own
+
+	repoPath string
+	mu       sync.Mutex // Serializes operations that change worktrees
+
+	clientsMu sync.Mutex
+	clients   map[*serveClient]bool
+
+	snapshotMu sync.Mutex
+	snapshot   []models.WorktreeInfo // Worktrees as last reported to the clients
+}
+
+// serveClient is a connected client. Notifications are queued and written by
+// the client's own goroutine, so a client that stops reading cannot hold up
+// the server or the other clients.
+type serveClient struct {
+	conn          net.Conn
+	writer        *rpcWriter
+	notifications chan *rpcNotification
+	sent          chan struct{} // Closed when the queue is drained
+}
+
+// rpcExecResult is the outcome of the exec method
+type rpcExecResult struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// NewServeCommand creates a new ServeCommand instance
+func NewServeCommand(gitService *services.GitService) *ServeCommand {
+	return &ServeCommand{
+		gitService: gitService,
+		interval:   defaultServeInterval,
+	}
+}
+
+// SetSocket sets the path of the unix socket to listen on
+func (sc *ServeCommand) SetSocket(socket string) {
+	sc.socket = socket
+}
+
+// SetInterval sets how often the worktrees are checked for changes made by
+// others; 0 disables the check
+func (sc *ServeCommand) SetInterval(interval time.Duration) {
+	sc.interval = interval
+}
+
+// Execute serves the repository on the socket until interrupted
+func (sc *ServeCommand) Execute(repoPath string) error {
+	if sc.socket == "" {
+		return fmt.Errorf("a socket path is required (--socket <path>)")
+	}
+	// Detect git once up front; requests are handled concurrently
+	if _, err := sc.gitService.ParsedGitVersion(); err != nil {
+		return err
+	}
+
+	listener, err := listenUnix(sc.socket)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Serving %s on %s\n", repoPath, sc.socket)
+	if err := sc.serve(ctx, repoPath, listener); err != nil {
+		return err
+	}
+	fmt.Println("Stopped")
+	return nil
+}
+
+// listenUnix listens on a unix socket at path, replacing a socket left behind
+// by a server that is no longer running
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	// Clients can run commands, so only the owner may connect. The socket is
+	// created with that mode rather than changed afterwards, which would leave
+	// a moment for another user to connect.
+	restore := restrictUmask()
+	listener, err := net.Listen("unix", path)
+	restore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// serve accepts connections on listener until ctx is done, then closes them
+func (sc *ServeCommand) serve(ctx context.Context, repoPath string, listener net.Listener) error {
+	sc.repoPath = repoPath
+	sc.clients = map[*serveClient]bool{}
+	snapshot, err := sc.worktrees()
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	sc.snapshot = snapshot
+
+	stopListening := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stopListening()
+	if sc.interval > 0 {
+		go sc.watch(ctx)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sc.handleConn(ctx, conn)
+		}()
+	}
+}
+
+// handleConn reads messages from a client until it disconnects. Requests are
+// handled concurrently, so responses may arrive in a different order.
+func (sc *ServeCommand) handleConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stopClosing := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopClosing()
+
+	client := sc.addClient(conn)
+	defer sc.removeClient(client)
+	writer := client.writer
+
+	var pending sync.WaitGroup
+	defer pending.Wait()
+	decoder := json.NewDecoder(conn)
+	for {
+		var message json.RawMessage
+		if err := decoder.Decode(&message); err != nil {
+			// The stream cannot be resynchronized after malformed JSON
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				writer.write(&rpcResponse{JSONRPC: "2.0", Error: newRPCError(rpcParseError, "parse error: %v", err)})
+			}
+			return
+		}
+		pending.Add(1)
+		go func() {
+			defer pending.Done()
+			if response := sc.handleMessage(message); response != nil {
+				if err := writer.write(response); err != nil {
+					// A message may have been cut off, so the stream is unusable
+					conn.Close()
+				}
+			}
+		}()
+	}
+}
+
+// addClient registers a connection for notifications and starts writing them
+func (sc *ServeCommand) addClient(conn net.Conn) *serveClient {
+	client := &serveClient{
+		conn:          conn,
+		writer:        &rpcWriter{w: conn, timeout: serveWriteTimeout},
+		notifications: make(chan *rpcNotification, notificationQueueSize),
+		sent:          make(chan struct{}),
+	}
+	go func() {
+		defer close(client.sent)
+		for notification := range client.notifications {
+			if err := client.writer.write(notification); err != nil {
+				// As for responses; the connection handler sees the closed
+				// connection and cleans up
+				conn.Close()
+			}
+		}
+	}()
+
+	sc.clientsMu.Lock()
+	sc.clients[client] = true
+	sc.clientsMu.Unlock()
+	return client
+}
+
+// removeClient stops sending notifications to a client and waits until the
+// ones already queued are written or failed
+func (sc *ServeCommand) removeClient(client *serveClient) {
+	sc.clientsMu.Lock()
+	delete(sc.clients, client)
+	close(client.notifications)
+	sc.clientsMu.Unlock()
+	<-client.sent
+}
+
+// handleMessage handles a request, notification or batch and returns what to
+// send back, or nil if nothing is
+func (sc *ServeCommand) handleMessage(message json.RawMessage) any {
+	message = bytes.TrimSpace(message)
+	if len(message) == 0 || message[0] != '[' {
+		if response := sc.handleRequest(message); response != nil {
+			return response
+		}
+		return nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(message, &batch); err != nil || len(batch) == 0 {
+		return &rpcResponse{JSONRPC: "2.0", Error: newRPCError(rpcInvalidRequest, "invalid batch")}
+	}
+	var responses []*rpcResponse
+	for _, m := range batch {
+		if response := sc.handleRequest(m); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses
+}
+
+// handleRequest calls the method of a single request. Notifications get no response.
+func (sc *ServeCommand) handleRequest(message json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(message, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return &rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: newRPCError(rpcInvalidRequest, "invalid request")}
+	}
+
+	result, rpcErr := sc.call(req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	response := &rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if rpcErr != nil {
+		response.Error = rpcErr
+	} else {
+		response.Result = result
+	}
+	return response
+}
+
+// call dispatches a method. A panic fails the request, not the server.
+func (sc *ServeCommand) call(method string, params json.RawMessage) (result any, rpcErr *rpcError) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, rpcErr = nil, newRPCError(rpcInternalError, "internal error: %v", r)
+		}
+	}()
+
+	switch method {
+	case "list":
+		return sc.list(params)
+	case "status":
+		return sc.status(params)
+	case "add":
+		return sc.add(params)
+	case "remove":
+		return sc.remove(params)
+	case "exec":
+		return sc.exec(params)
+	case "switch":
+		return sc.switchTo(params)
+	default:
+		return nil, newRPCError(rpcMethodNotFound, "method %q not found", method)
+	}
+}
+
+// list returns the worktrees, optionally filtered like 'wt list -f/-b'
+func (sc *ServeCommand) list(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		Filter string `json:"filter"`
+		Branch string `json:"branch"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	worktrees, err := sc.gitService.GetWorktrees(sc.repoPath)
+	if err != nil {
+		return nil, commandFailed(err)
+	}
+	lc := NewListCommand(sc.gitService)
+	lc.SetFilter(p.Filter)
+	lc.SetBranch(p.Branch)
+	return models.Infos(lc.applyFilters(worktrees)), nil
+}
+
+// status returns what 'wt status -j' prints
+func (sc *ServeCommand) status(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		Dirty    bool `json:"dirty"`
+		Unpushed bool `json:"unpushed"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	stc := NewStatusCommand(sc.gitService)
+	stc.SetDirtyOnly(p.Dirty)
+	stc.SetUnpushedOnly(p.Unpushed)
+	statuses, err := stc.collect(sc.repoPath)
+	if err != nil {
+		return nil, commandFailed(err)
+	}
+	if statuses == nil {
+		statuses = []*models.WorktreeStatus{}
+	}
+	return statuses, nil
+}
+
+// add creates a worktree like 'wt add' and returns it
+func (sc *ServeCommand) add(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		Branch       string   `json:"branch"`
+		Create       bool     `json:"create"`
+		StartPoint   string   `json:"start_point"`
+		Name         string   `json:"name"`
+		Exec         []string `json:"exec"`
+		Lock         bool     `json:"lock"`
+		Reason       string   `json:"reason"`
+		NoSubmodules bool     `json:"no_submodules"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Branch == "" {
+		return nil, newRPCError(rpcInvalidParams, "branch is required")
+	}
+
+	ac := NewAddCommand(sc.gitService)
+	ac.SetInput(nil) // Nobody is there to answer prompts
+	ac.SetCreateBranch(p.Create)
+	ac.SetBranchName(p.Branch)
+	ac.SetStartPoint(p.StartPoint)
+	ac.SetDirName(p.Name)
+	ac.SetLock(p.Lock, p.Reason)
+	ac.SetNoSubmodules(p.NoSubmodules)
+	for _, command := range p.Exec {
+		cmd := utils.NewCommand("sh", []string{"-c", command})
+		if err := cmd.Validate(); err != nil {
+			return nil, newRPCError(rpcInvalidParams, "invalid exec command: %v", err)
+		}
+		ac.AddExecCommand(cmd)
+	}
+
+	sc.mu.Lock()
+	err := ac.Execute(sc.repoPath)
+	sc.mu.Unlock()
+	sc.refresh()
+	if err != nil {
+		return nil, commandFailed(err)
+	}
+	return sc.findServedWorktree(ac.worktreePath)
+}
+
+// remove removes a worktree like 'wt remove' and returns it
+func (sc *ServeCommand) remove(params json.RawMessage) (any, *rpcError) {
+	wt, rpcErr := sc.worktreeParam(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	rc := NewRemoveCommand(sc.gitService)
+	rc.SetWorktree(wt.Path)
+
+	sc.mu.Lock()
+	err := rc.Execute(sc.repoPath)
+	sc.mu.Unlock()
+	sc.refresh()
+	if err != nil {
+		return nil, commandFailed(err)
+	}
+	return wt.Info(), nil
+}
+
+// exec runs a command in a worktree and returns its output. A command that
+// exits non-zero is not an error; one that cannot be run is.
+func (sc *ServeCommand) exec(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		Worktree string   `json:"worktree"`
+		Command  []string `json:"command"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Command) == 0 {
+		return nil, newRPCError(rpcInvalidParams, "command is required")
+	}
+	wt, rpcErr := sc.findWorktreeParam(p.Worktree)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	cmd := utils.NewCommand(p.Command[0], p.Command[1:])
+	cmd.Dir = wt.Path
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("WT_WORKTREE=%s", wt.Path),
+		fmt.Sprintf("WT_BRANCH=%s", wt.Branch),
+	)
+	result, err := cmd.Execute()
+	if result == nil || (err != nil && result.ExitCode <= 0) {
+		return nil, commandFailed(err)
+	}
+	return rpcExecResult{ExitCode: result.ExitCode, Stdout: result.Stdout, Stderr: result.Stderr}, nil
+}
+
+// switchTo returns a worktree and tells every client that it was switched to,
+// so that editors can follow a switch made elsewhere
+func (sc *ServeCommand) switchTo(params json.RawMessage) (any, *rpcError) {
+	wt, rpcErr := sc.worktreeParam(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	switched := wt.Info()
+	sc.broadcast(notifySwitched, map[string]any{"worktree": switched})
+	return switched, nil
+}
+
+// worktreeParam returns the worktree named by the "worktree" parameter
+func (sc *ServeCommand) worktreeParam(params json.RawMessage) (models.Worktree, *rpcError) {
+	var p struct {
+		Worktree string `json:"worktree"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return models.Worktree{}, err
+	}
+	return sc.findWorktreeParam(p.Worktree)
+}
+
+// findWorktreeParam finds a worktree by path, directory name or branch
+func (sc *ServeCommand) findWorktreeParam(arg string) (models.Worktree, *rpcError) {
+	if arg == "" {
+		return models.Worktree{}, newRPCError(rpcInvalidParams, "worktree is required")
+	}
+	worktrees, err := sc.gitService.GetWorktrees(sc.repoPath)
+	if err != nil {
+		return models.Worktree{}, commandFailed(err)
+	}
+	wt, err := FindWorktree(worktrees, arg)
+	if err != nil {
+		return models.Worktree{}, commandFailed(err)
+	}
+	return wt, nil
+}
+
+// findServedWorktree returns the worktree at path
+func (sc *ServeCommand) findServedWorktree(path string) (any, *rpcError) {
+	worktrees, err := sc.gitService.GetWorktrees(sc.repoPath)
+	if err != nil {
+		return nil, commandFailed(err)
+	}
+	for _, wt := range worktrees {
+		if samePath(wt.Path, path) {
+			return wt.Info(), nil
+		}
+	}
+	return nil, commandFailed(fmt.Errorf("worktree %s not found", path))
+}
+
+// watch checks the worktrees for changes every interval until ctx is done
+func (sc *ServeCommand) watch(ctx context.Context) {
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sc.refresh()
+		}
+	}
+}
+
+// refresh notifies the clients if the worktrees changed since they were last told.
+// broadcast only queues the notification, so holding snapshotMu while calling it
+// keeps notifications in snapshot order without waiting for any client.
+func (sc *ServeCommand) refresh() {
+	worktrees, err := sc.worktrees()
+	if err != nil {
+		return
+	}
+	sc.snapshotMu.Lock()
+	defer sc.snapshotMu.Unlock()
+	if reflect.DeepEqual(worktrees, sc.snapshot) {
+		return
+	}
+	sc.snapshot = worktrees
+	sc.broadcast(notifyChanged, map[string]any{"worktrees": worktrees})
+}
+
+// worktrees returns every worktree of the served repository
+func (sc *ServeCommand) worktrees() ([]models.WorktreeInfo, error) {
+	worktrees, err := sc.gitService.GetWorktrees(sc.repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	return models.Infos(worktrees), nil
+}
+
+// broadcast queues a notification for every connected client without waiting.
+// A client whose queue is full is disconnected rather than sent an incomplete
+// picture; its connection handler cleans up.
+func (sc *ServeCommand) broadcast(method string, params any) {
+	notification := &rpcNotification{JSONRPC: "2.0", Method: method, Params: params}
+	sc.clientsMu.Lock()
+	defer sc.clientsMu.Unlock()
+	for client := range sc.clients {
+		select {
+		case client.notifications <- notification:
+		default:
+			client.conn.Close()
+		}
+	}
+}
+
+// commandFailed reports a failed wt operation to the client
+func commandFailed(err error) *rpcError {
+	return &rpcError{Code: rpcCommandFailed, Message: err.Error()}
+}
+
+// RunServeCommand is the entry point for the serve command
+func RunServeCommand(repoPath, gitPath, socket string, interval time.Duration) error {
+	gitService := services.NewGitService(gitPath)
+	serveCmd := NewServeCommand(gitService)
+	serveCmd.SetSocket(socket)
+	serveCmd.SetInterval(interval)
+
+	return serveCmd.Execute(repoPath)
+}
@@ -0,0 +1,290 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// rpcClient is a test client for 'wt serve' that keeps the notifications it
+// reads while waiting for responses
+type rpcClient struct {
+	t             *testing.T
+	conn          net.Conn
+	decoder       *json.Decoder
+	nextID        int
+	notifications []map[string]any
+}
+
+// startServer serves repoDir on a socket in a temporary directory and connects to it
+func startServer(t *testing.T, repoDir string, interval time.Duration) *rpcClient {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "wt.sock")
+	listener, err := listenUnix(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewServeCommand(services.NewGitService(""))
+	sc.SetInterval(interval)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- sc.serve(ctx, repoDir, listener) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serve: %v", err)
+		}
+	})
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	client := &rpcClient{t: t, conn: conn, decoder: json.NewDecoder(conn)}
+	// Once a request is answered, the server has its first snapshot and sends
+	// this client notifications, so no change made by the test is missed
+	if _, rpcErr := client.call("list", nil); rpcErr != nil {
+		t.Fatalf("list failed: %v", rpcErr.Message)
+	}
+	return client
+}
+
+// send writes a raw message
+func (c *rpcClient) send(message string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(message + "\n")); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// read returns the next message that is not a notification
+func (c *rpcClient) read() json.RawMessage {
+	c.t.Helper()
+	for {
+		var message json.RawMessage
+		if err := c.decoder.Decode(&message); err != nil {
+			c.t.Fatalf("read: %v", err)
+		}
+		var notification map[string]any
+		if json.Unmarshal(message, &notification) == nil && notification["method"] != nil {
+			c.notifications = append(c.notifications, notification)
+			continue
+		}
+		return message
+	}
+}
+
+// call sends a request and returns its result, or its error if it failed
+func (c *rpcClient) call(method string, params any) (json.RawMessage, *rpcError) {
+	c.t.Helper()
+	c.nextID++
+	request, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	c.send(string(request))
+
+	var response struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(c.read(), &response); err != nil {
+		c.t.Fatal(err)
+	}
+	if response.ID != c.nextID {
+		c.t.Fatalf("response id = %d, want %d", response.ID, c.nextID)
+	}
+	return response.Result, response.Error
+}
+
+// waitForNotification reads until a notification of method arrives
+func (c *rpcClient) waitForNotification(method string) map[string]any {
+	c.t.Helper()
+	for {
+		for i, n := range c.notifications {
+			if n["method"] == method {
+				c.notifications = append(c.notifications[:i], c.notifications[i+1:]...)
+				return n
+			}
+		}
+		var message map[string]any
+		if err := c.decoder.Decode(&message); err != nil {
+			c.t.Fatalf("waiting for %s: %v", method, err)
+		}
+		if message["method"] != nil {
+			c.notifications = append(c.notifications, message)
+		}
+	}
+}
+
+func TestServeWorktreeMethods(t *testing.T) {
+	repoDir := newTestRepo(t)
+	client := startServer(t, repoDir, 0)
+
+	result, rpcErr := client.call("add", map[string]any{"branch": "feature/x", "create": true, "exec": []string{"touch marker"}})
+	if rpcErr != nil {
+		t.Fatalf("add failed: %v", rpcErr.Message)
+	}
+	var added models.WorktreeInfo
+	json.Unmarshal(result, &added)
+	if added.Branch != "feature/x" || added.Name != "feature-x" || !exists(filepath.Join(added.Path, "marker")) {
+		t.Errorf("add returned %+v", added)
+	}
+	changed := client.waitForNotification(notifyChanged)
+	if worktrees := changed["params"].(map[string]any)["worktrees"].([]any); len(worktrees) != 2 {
+		t.Errorf("changed notification lists %d worktrees, want 2", len(worktrees))
+	}
+
+	result, rpcErr = client.call("list", map[string]any{"branch": "feature/x"})
+	if rpcErr != nil || !strings.Contains(string(result), `"path":"`+added.Path+`"`) || strings.Contains(string(result), repoDir+`"`) {
+		t.Errorf("list = %s, %v", result, rpcErr)
+	}
+
+	result, rpcErr = client.call("status", map[string]any{"dirty": true})
+	if rpcErr != nil || !strings.Contains(string(result), `"untracked":1`) {
+		t.Errorf("status = %s, %v", result, rpcErr)
+	}
+
+	result, rpcErr = client.call("exec", map[string]any{"worktree": "feature/x", "command": []string{"sh", "-c", "pwd; exit 3"}})
+	var execResult rpcExecResult
+	json.Unmarshal(result, &execResult)
+	if rpcErr != nil || execResult.ExitCode != 3 || !samePath(strings.TrimSpace(execResult.Stdout), added.Path) {
+		t.Errorf("exec = %s, %v", result, rpcErr)
+	}
+
+	if _, rpcErr = client.call("switch", map[string]any{"worktree": "feature-x"}); rpcErr != nil {
+		t.Errorf("switch failed: %v", rpcErr.Message)
+	}
+	switched := client.waitForNotification(notifySwitched)
+	if path := switched["params"].(map[string]any)["worktree"].(map[string]any)["path"]; path != added.Path {
+		t.Errorf("switched to %v, want %s", path, added.Path)
+	}
+
+	// The marker makes the worktree dirty, so git refuses to remove it
+	if _, rpcErr = client.call("remove", map[string]any{"worktree": "feature/x"}); rpcErr == nil || rpcErr.Code != rpcCommandFailed {
+		t.Errorf("expected remove of a dirty worktree to fail, got %+v", rpcErr)
+	}
+	runTestGit(t, added.Path, "clean", "-fq")
+	if _, rpcErr = client.call("remove", map[string]any{"worktree": "feature/x"}); rpcErr != nil {
+		t.Fatalf("remove failed: %v", rpcErr.Message)
+	}
+	if exists(added.Path) {
+		t.Errorf("%s still exists", added.Path)
+	}
+	client.waitForNotification(notifyChanged)
+}
+
+func TestServeProtocolErrors(t *testing.T) {
+	repoDir := newTestRepo(t)
+	client := startServer(t, repoDir, 0)
+
+	for _, tc := range []struct {
+		method string
+		params any
+		code   int
+	}{
+		{"nope", nil, rpcMethodNotFound},
+		{"add", []string{"feature"}, rpcInvalidParams},
+		{"add", map[string]any{}, rpcInvalidParams},
+		{"remove", map[string]any{"worktree": "missing"}, rpcCommandFailed},
+	} {
+		if _, rpcErr := client.call(tc.method, tc.params); rpcErr == nil || rpcErr.Code != tc.code {
+			t.Errorf("%s %v: error = %+v, want code %d", tc.method, tc.params, rpcErr, tc.code)
+		}
+	}
+
+	// Notifications get no response, so the next message answers the batch
+	client.send(`{"jsonrpc":"2.0","method":"list"}`)
+	client.send(`[{"jsonrpc":"2.0","id":"a","method":"list"},{"jsonrpc":"2.0","method":"list"},{"id":"b"}]`)
+	var batch []struct {
+		ID     string    `json:"id"`
+		Result any       `json:"result"`
+		Error  *rpcError `json:"error"`
+	}
+	if err := json.Unmarshal(client.read(), &batch); err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 2 || batch[0].ID != "a" || batch[0].Result == nil || batch[1].ID != "b" || batch[1].Error.Code != rpcInvalidRequest {
+		t.Errorf("batch response = %+v", batch)
+	}
+
+	client.send(`{"jsonrpc":`)
+	client.send(`}`)
+	var response rpcResponse
+	if err := json.Unmarshal(client.read(), &response); err != nil || response.Error == nil || response.Error.Code != rpcParseError {
+		t.Errorf("malformed JSON: response = %+v, %v", response, err)
+	}
+}
+
+func TestServeNotifiesExternalChanges(t *testing.T) {
+	repoDir := newTestRepo(t)
+	client := startServer(t, repoDir, 20*time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "outside")
+	runTestGit(t, repoDir, "worktree", "add", "-q", "-b", "outside", path)
+	changed := client.waitForNotification(notifyChanged)
+	if !strings.Contains(fmt.Sprint(changed["params"]), path) {
+		t.Errorf("changed notification does not list %s: %v", path, changed)
+	}
+}
+
+func TestServeDisconnectsSlowClients(t *testing.T) {
+	sc := NewServeCommand(services.NewGitService(""))
+	sc.clients = map[*serveClient]bool{}
+	server, peer := net.Pipe()
+	defer peer.Close()
+	client := sc.addClient(server)
+	defer sc.removeClient(client)
+
+	// The peer never reads, so the queue fills up; broadcasting must not wait for it
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < notificationQueueSize+2; i++ {
+			sc.broadcast(notifySwitched, nil)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("broadcast blocked on a client that does not read")
+	}
+
+	peer.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err := io.Copy(io.Discard, peer); err != nil {
+		t.Errorf("slow client was not disconnected: %v", err)
+	}
+}
+
+func TestListenUnixRefusesSocketInUse(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "wt.sock")
+	listener, err := listenUnix(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if info, err := os.Stat(socket); err != nil {
+		t.Fatal(err)
+	} else if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("socket mode = %v, want 0600", mode)
+	}
+	if _, err := listenUnix(socket); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("expected socket in use to be refused, got %v", err)
+	}
+
+	notSocket := filepath.Join(dir, "file")
+	runTestGit(t, dir, "init", "-q", notSocket)
+	if _, err := listenUnix(notSocket); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("expected non-socket to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+// Sparse command implementation
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// SparseCommand handles the 'wt sparse add|remove|list' command
+type SparseCommand struct {
+	gitService *services.GitService
+	action     string   // add, remove or list
+	dirs       []string // Directories to add or remove
+	profiles   []string // Sparse profiles whose directories are added or removed
+}
+
+// NewSparseCommand creates a new SparseCommand instance
+func NewSparseCommand(gitService *services.GitService) *SparseCommand {
+	return &SparseCommand{
+		gitService: gitService,
+	}
+}
+
+// SetAction sets the subcommand to run: add, remove or list
+func (sc *SparseCommand) SetAction(action string) {
+	sc.action = action
+}
+
+// SetDirs sets the directories to add or remove, directly and through sparse profiles
+func (sc *SparseCommand) SetDirs(dirs, profiles []string) {
+	sc.dirs = dirs
+	sc.profiles = profiles
+}
+
+// Execute adjusts or lists the sparse-checkout of the worktree at worktreePath
+func (sc *SparseCommand) Execute(worktreePath string) error {
+	if sc.action == "list" {
+		dirs, err := sc.gitService.GetSparseDirs(worktreePath)
+		if err != nil {
+			return err
+		}
+		for _, dir := range dirs {
+			fmt.Println(dir)
+		}
+		return nil
+	}
+
+	cfg, err := sc.gitService.LoadConfig(worktreePath)
+	if err != nil {
+		return err
+	}
+	profileDirs, err := sparseProfileDirs(cfg, sc.profiles)
+	if err != nil {
+		return err
+	}
+	dirs := append(append([]string{}, sc.dirs...), profileDirs...)
+	if len(dirs) == 0 {
+		return fmt.Errorf("no directories given")
+	}
+
+	switch sc.action {
+	case "add":
+		if err := sc.gitService.AddSparseDirs(worktreePath, dirs); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Checked out %s\n", strings.Join(dirs, ", "))
+		return nil
+	case "remove":
+		return sc.remove(worktreePath, dirs)
+	default:
+		return fmt.Errorf("unknown sparse action %q (want add, remove or list)", sc.action)
+	}
+}
+
+// remove drops dirs from the sparse-checkout. Git has no command for this,
+// so the remaining directories are set again.
+func (sc *SparseCommand) remove(worktreePath string, dirs []string) error {
+	current, err := sc.gitService.GetSparseDirs(worktreePath)
+	if err != nil {
+		return err
+	}
+
+	var remaining []string
+	for _, dir := range current {
+		if !slices.Contains(dirs, dir) && !slices.Contains(dirs, dir+"/") {
+			remaining = append(remaining, dir)
+		}
+	}
+	for _, dir := range dirs {
+		if !slices.Contains(current, strings.TrimSuffix(dir, "/")) {
+			fmt.Printf("! %s is not checked out\n", dir)
+		}
+	}
+	if len(remaining) == len(current) {
+		return nil
+	}
+	if len(remaining) == 0 {
+		return fmt.Errorf("cannot remove every directory; run 'git sparse-checkout disable' to check out everything")
+	}
+
+	if err := sc.gitService.SetSparseDirs(worktreePath, remaining); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Removed %s\n", strings.Join(dirs, ", "))
+	return nil
+}
+
+// sparseProfileDirs returns the directories of the named wt.sparse.<name> profiles
+func sparseProfileDirs(cfg *models.Config, profiles []string) ([]string, error) {
+	var dirs []string
+	for _, name := range profiles {
+		// git config lower-cases the last part of wt.sparse.<name>
+		profile, ok := cfg.SparseProfiles[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown sparse profile %q (define it with 'git config --add wt.sparse.%s <dir>')", name, name)
+		}
+		dirs = append(dirs, profile...)
+	}
+	return dirs, nil
+}
+
+// RunSparseCommand is the entry point for the sparse command
+func RunSparseCommand(worktreePath, gitPath, action string, dirs, profiles []string) error {
+	gitService := services.NewGitService(gitPath)
+	sparseCmd := NewSparseCommand(gitService)
+	sparseCmd.SetAction(action)
+	sparseCmd.SetDirs(dirs, profiles)
+
+	return sparseCmd.Execute(worktreePath)
+}
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// newMonorepo creates a test repository with the services api, web and worker
+func newMonorepo(t *testing.T) string {
+	t.Helper()
+	repoDir := newTestRepo(t)
+	for _, dir := range []string{"services/api", "services/web", "services/worker"} {
+		if err := os.MkdirAll(filepath.Join(repoDir, dir), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(repoDir, dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	runTestGit(t, repoDir, "add", ".")
+	runTestGit(t, repoDir, "commit", "-m", "services")
+	return repoDir
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestAddSparseAndAdjust(t *testing.T) {
+	repoDir := newMonorepo(t)
+	gs := services.NewGitService("")
+	runTestGit(t, repoDir, "config", "--add", "wt.sparse.Frontend", "services/web")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("feature-api")
+	ac.SetSparse([]string{"services/api"}, []string{"frontend"})
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add --sparse failed: %v", err)
+	}
+
+	wtPath := filepath.Join(gs.GetWorktreesDir(repoDir), "feature-api")
+	if !exists(filepath.Join(wtPath, "services/api/main.go")) || !exists(filepath.Join(wtPath, "services/web/main.go")) {
+		t.Errorf("sparse directories were not checked out")
+	}
+	if exists(filepath.Join(wtPath, "services/worker")) {
+		t.Errorf("services/worker should not be checked out")
+	}
+	if !exists(filepath.Join(wtPath, "README.md")) {
+		t.Errorf("cone mode should check out top-level files")
+	}
+	if status := runTestGit(t, wtPath, "status", "--porcelain"); status != "" {
+		t.Errorf("sparse worktree is not clean:\n%s", status)
+	}
+	if exists(filepath.Join(repoDir, ".git", "info", "sparse-checkout")) {
+		t.Errorf("sparse-checkout leaked into the main worktree")
+	}
+
+	sc := NewSparseCommand(gs)
+	sc.SetAction("add")
+	sc.SetDirs([]string{"services/worker"}, nil)
+	if _, err := captureStdout(t, func() error { return sc.Execute(wtPath) }); err != nil {
+		t.Fatalf("sparse add failed: %v", err)
+	}
+	if !exists(filepath.Join(wtPath, "services/worker/main.go")) {
+		t.Errorf("services/worker was not added")
+	}
+
+	sc = NewSparseCommand(gs)
+	sc.SetAction("remove")
+	sc.SetDirs([]string{"services/api/"}, []string{"frontend"})
+	if _, err := captureStdout(t, func() error { return sc.Execute(wtPath) }); err != nil {
+		t.Fatalf("sparse remove failed: %v", err)
+	}
+	if exists(filepath.Join(wtPath, "services/api")) || exists(filepath.Join(wtPath, "services/web")) {
+		t.Errorf("removed directories are still checked out")
+	}
+
+	sc = NewSparseCommand(gs)
+	sc.SetAction("list")
+	output, err := captureStdout(t, func() error { return sc.Execute(wtPath) })
+	if err != nil {
+		t.Fatalf("sparse list failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "services/worker" {
+		t.Errorf("sparse list = %q, want services/worker", output)
+	}
+
+	sc = NewSparseCommand(gs)
+	sc.SetAction("remove")
+	sc.SetDirs([]string{"services/worker"}, nil)
+	if err := sc.Execute(wtPath); err == nil {
+		t.Errorf("expected removing the last directory to fail")
+	}
+}
+
+func TestAddSparseUnknownProfile(t *testing.T) {
+	repoDir := newMonorepo(t)
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("feature-x")
+	ac.SetSparse(nil, []string{"missing"})
+	_, err := captureStdout(t, func() error { return ac.Execute(repoDir) })
+	if err == nil || !strings.Contains(err.Error(), "unknown sparse profile") {
+		t.Fatalf("expected unknown profile error, got %v", err)
+	}
+	if gs.BranchExists(repoDir, "feature-x") {
+		t.Errorf("branch was created despite the error")
+	}
+}
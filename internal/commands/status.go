@@ -0,0 +1,275 @@
+// Status command implementation
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+	"github.com/smoerfugl/wt/internal/utils"
+)
+
+// StatusCommand handles the 'wt status' command
+type StatusCommand struct {
+	gitService   *services.GitService
+	jsonOutput   bool
+	dirtyOnly    bool
+	unpushedOnly bool
+	allRepos     bool
+	now          func() time.Time // Injectable clock for commit ages
+}
+
+// NewStatusCommand creates a new StatusCommand instance
+func NewStatusCommand(gitService *services.GitService) *StatusCommand {
+	return &StatusCommand{
+		gitService: gitService,
+		now:        time.Now,
+	}
+}
+
+// SetJSONOutput sets JSON output mode
+func (sc *StatusCommand) SetJSONOutput(jsonOutput bool) {
+	sc.jsonOutput = jsonOutput
+}
+
+// SetDirtyOnly only shows worktrees with uncommitted changes
+func (sc *StatusCommand) SetDirtyOnly(dirtyOnly bool) {
+	sc.dirtyOnly = dirtyOnly
+}
+
+// SetUnpushedOnly only shows worktrees whose branch has commits that are not pushed
+func (sc *StatusCommand) SetUnpushedOnly(unpushedOnly bool) {
+	sc.unpushedOnly = unpushedOnly
+}
+
+// SetAllRepos shows the worktrees of every registered repository
+func (sc *StatusCommand) SetAllRepos(allRepos bool) {
+	sc.allRepos = allRepos
+}
+
+// Execute prints the status of every worktree
+func (sc *StatusCommand) Execute(repoPath string) error {
+	var statuses []*models.WorktreeStatus
+	var err error
+	if sc.allRepos {
+		statuses, err = sc.collectAllRepos()
+	} else {
+		statuses, err = sc.collect(repoPath)
+	}
+	if err != nil {
+		return err
+	}
+	return sc.Print(statuses)
+}
+
+// Print writes statuses in the selected output format
+func (sc *StatusCommand) Print(statuses []*models.WorktreeStatus) error {
+	if sc.jsonOutput {
+		if statuses == nil {
+			statuses = []*models.WorktreeStatus{}
+		}
+		output, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format status as JSON: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	fmt.Print(sc.formatStatus(statuses))
+	return nil
+}
+
+// Statuses returns the status of each worktree of the repository that passes the filters
+func (sc *StatusCommand) Statuses(repoPath string) ([]*models.WorktreeStatus, error) {
+	return sc.collect(repoPath)
+}
+
+// collect returns the status of each worktree that passes the filters
+func (sc *StatusCommand) collect(repoPath string) ([]*models.WorktreeStatus, error) {
+	worktrees, err := sc.gitService.GetWorktrees(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktrees: %w", err)
+	}
+
+	var statuses []*models.WorktreeStatus
+	for _, wt := range worktrees {
+		if wt.IsBare {
+			// The repository of a hub layout has no files of its own
+			continue
+		}
+		status := &models.WorktreeStatus{}
+		if _, statErr := os.Stat(wt.Path); os.IsNotExist(statErr) {
+			// git does not mark locked worktrees prunable, so check for
+			// ones whose disk is not mounted here
+			status.Missing = true
+		} else if !wt.IsPrunable() {
+			if status, err = sc.gitService.GetWorktreeStatus(wt.Path); err != nil {
+				return nil, err
+			}
+		}
+		status.Name = wt.Name
+		status.Path = wt.Path
+		status.Branch = wt.Branch
+		status.Locked = wt.IsLocked
+		status.LockReason = wt.LockReason
+		status.Prunable = wt.PrunableReason
+
+		if sc.dirtyOnly && !status.IsDirty() {
+			continue
+		}
+		if sc.unpushedOnly && !status.IsUnpushed() {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// collectAllRepos returns the status of the worktrees of every registered
+// repository. Repositories that cannot be read are reported and skipped.
+func (sc *StatusCommand) collectAllRepos() ([]*models.WorktreeStatus, error) {
+	results, err := forEachRepo(sc.gitService, func(repo models.RegisteredRepo) ([]*models.WorktreeStatus, error) {
+		return sc.collect(repo.Path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*models.WorktreeStatus
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s (%s): %v\n", r.repo.Name, r.repo.Path, r.err)
+			continue
+		}
+		for _, status := range r.value {
+			status.Repo = r.repo.Name
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// formatStatus renders one compact line per worktree
+func (sc *StatusCommand) formatStatus(statuses []*models.WorktreeStatus) string {
+	if len(statuses) == 0 {
+		return "No matching worktrees\n"
+	}
+
+	header := []string{"NAME", "BRANCH", "CHANGES", "SYNC", "AGE", "STATE", "LAST COMMIT"}
+	if sc.allRepos {
+		header = append([]string{"REPO"}, header...)
+	}
+	rows := [][]string{header}
+	for _, s := range statuses {
+		branch := s.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+		age := ""
+		if s.LastCommitTime != nil {
+			age = utils.FormatAge(sc.now().Sub(*s.LastCommitTime))
+		}
+		row := []string{s.Name, branch, formatChanges(s), formatSync(s), age, formatState(s), s.LastCommitSubject}
+		if sc.allRepos {
+			row = append([]string{s.Repo}, row...)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+
+	var result strings.Builder
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			if i == len(row)-1 {
+				line.WriteString(cell)
+			} else {
+				line.WriteString(cell + strings.Repeat(" ", widths[i]-len([]rune(cell))+2))
+			}
+		}
+		result.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	return result.String()
+}
+
+// formatChanges shows staged (+), modified (~), untracked (?) and conflicted (!) file counts
+func formatChanges(s *models.WorktreeStatus) string {
+	if s.Prunable != "" || s.Missing {
+		return "-"
+	}
+	if !s.IsDirty() {
+		return "clean"
+	}
+	var parts []string
+	for _, c := range []struct {
+		mark  string
+		count int
+	}{{"+", s.Staged}, {"~", s.Modified}, {"?", s.Untracked}, {"!", s.Conflicts}} {
+		if c.count > 0 {
+			parts = append(parts, fmt.Sprintf("%s%d", c.mark, c.count))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatSync shows how far the branch is ahead of (↑) and behind (↓) its upstream
+func formatSync(s *models.WorktreeStatus) string {
+	switch {
+	case s.Branch == "" || s.Prunable != "" || s.Missing:
+		return "-"
+	case s.Upstream == "":
+		return "no upstream"
+	case s.Ahead == 0 && s.Behind == 0:
+		return "="
+	}
+	var parts []string
+	if s.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d", s.Ahead))
+	}
+	if s.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", s.Behind))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatState shows the in-progress operation, lock and prunable state
+func formatState(s *models.WorktreeStatus) string {
+	var parts []string
+	if s.Operation != "" {
+		parts = append(parts, s.Operation+" in progress")
+	}
+	if s.Locked && s.LockReason != "" {
+		parts = append(parts, "locked: "+s.LockReason)
+	} else if s.Locked {
+		parts = append(parts, "locked")
+	}
+	if s.Prunable != "" {
+		parts = append(parts, "prunable: "+s.Prunable)
+	} else if s.Missing {
+		parts = append(parts, "unavailable: directory missing")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// RunStatusCommand is the entry point for the status command
+func RunStatusCommand(repoPath, gitPath string, jsonOutput, dirtyOnly, unpushedOnly, allRepos bool) error {
+	gitService := services.NewGitService(gitPath)
+	statusCmd := NewStatusCommand(gitService)
+	statusCmd.SetJSONOutput(jsonOutput)
+	statusCmd.SetDirtyOnly(dirtyOnly)
+	statusCmd.SetUnpushedOnly(unpushedOnly)
+	statusCmd.SetAllRepos(allRepos)
+
+	return statusCmd.Execute(repoPath)
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+func TestStatusFiltersAndOperations(t *testing.T) {
+	repoDir := newTestRepo(t)
+	addTestRemote(t, repoDir, "origin")
+	gs := services.NewGitService("")
+
+	// pushed: in sync with its upstream
+	pushed := addTestWorktree(t, gs, repoDir, "pushed")
+	runTestGit(t, pushed, "push", "-q", "-u", "origin", "pushed")
+
+	// dirty: uncommitted and untracked files
+	dirty := addTestWorktree(t, gs, repoDir, "dirty")
+	runTestGit(t, dirty, "push", "-q", "-u", "origin", "dirty")
+	if err := os.WriteFile(filepath.Join(dirty, "README.md"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dirty, "new.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// merging: a merge stopped on a conflict
+	merging := addTestWorktree(t, gs, repoDir, "merging")
+	runTestGit(t, merging, "push", "-q", "-u", "origin", "merging")
+	runTestGit(t, merging, "checkout", "-q", "-b", "other")
+	os.WriteFile(filepath.Join(merging, "README.md"), []byte("other"), 0644)
+	runTestGit(t, merging, "commit", "-q", "-am", "other")
+	runTestGit(t, merging, "checkout", "-q", "merging")
+	os.WriteFile(filepath.Join(merging, "README.md"), []byte("merging"), 0644)
+	runTestGit(t, merging, "commit", "-q", "-am", "unpushed change")
+	merge := exec.Command("git", "merge", "other")
+	merge.Dir = merging
+	if err := merge.Run(); err == nil {
+		t.Fatal("expected the merge to conflict")
+	}
+
+	sc := NewStatusCommand(gs)
+	output, err := captureStdout(t, func() error { return sc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	for _, want := range []string{"~1 ?1", "merge in progress", "↑1", "unpushed change", "="} {
+		if !strings.Contains(output, want) {
+			t.Errorf("status output missing %q:\n%s", want, output)
+		}
+	}
+
+	sc = NewStatusCommand(gs)
+	sc.SetDirtyOnly(true)
+	sc.SetJSONOutput(true)
+	output, err = captureStdout(t, func() error { return sc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("status --dirty failed: %v", err)
+	}
+	var statuses []models.WorktreeStatus
+	if err := json.Unmarshal([]byte(output), &statuses); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	names := map[string]bool{}
+	for _, s := range statuses {
+		names[s.Name] = true
+	}
+	if !names["dirty"] || !names["merging"] || names["pushed"] {
+		t.Errorf("--dirty returned %v, want dirty and merging", names)
+	}
+
+	sc = NewStatusCommand(gs)
+	sc.SetUnpushedOnly(true)
+	output, err = captureStdout(t, func() error { return sc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("status --unpushed failed: %v", err)
+	}
+	if !strings.Contains(output, "\nmerging ") || strings.Contains(output, "\npushed ") || strings.Contains(output, "\ndirty ") {
+		t.Errorf("--unpushed output unexpected:\n%s", output)
+	}
+}
+
+func TestStatusReportsMissingLockedWorktree(t *testing.T) {
+	repoDir := newTestRepo(t)
+	gs := services.NewGitService("")
+	// A worktree on a disk that is not plugged in: locked, so git does not
+	// consider it prunable, but its directory is gone
+	usb := addTestWorktree(t, gs, repoDir, "usb")
+	runTestGit(t, repoDir, "worktree", "lock", "--reason", "on USB disk", usb)
+	if err := os.RemoveAll(usb); err != nil {
+		t.Fatal(err)
+	}
+
+	sc := NewStatusCommand(gs)
+	output, err := captureStdout(t, func() error { return sc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("status failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "unavailable") || !strings.Contains(output, "locked: on USB disk") {
+		t.Errorf("missing worktree not reported as unavailable:\n%s", output)
+	}
+
+	statuses, err := sc.Statuses(repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.Name == "usb" && !s.Missing {
+			t.Errorf("status of usb = %+v, want Missing", s)
+		}
+	}
+}
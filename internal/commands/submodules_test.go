@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// newRepoWithSubmodule creates a repository with a submodule at libs/sub
+func newRepoWithSubmodule(t *testing.T) string {
+	t.Helper()
+	// git refuses to clone submodules over the file transport by default
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	subDir := newTestRepo(t)
+	repoDir := newTestRepo(t)
+	runTestGit(t, repoDir, "submodule", "add", "-q", subDir, "libs/sub")
+	runTestGit(t, repoDir, "commit", "-m", "add submodule")
+	return repoDir
+}
+
+func TestAddInitializesSubmodules(t *testing.T) {
+	repoDir := newRepoWithSubmodule(t)
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("with-subs")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	subPath := filepath.Join(gs.GetWorktreesDir(repoDir), "with-subs", "libs", "sub")
+	if _, err := os.Stat(filepath.Join(subPath, "README.md")); err != nil {
+		t.Fatalf("submodule was not checked out: %v", err)
+	}
+
+	// Objects are copied from the main worktree's store rather than borrowed
+	gitDir := runTestGit(t, subPath, "rev-parse", "--absolute-git-dir")
+	if _, err := os.Stat(filepath.Join(gitDir, "objects", "info", "alternates")); err == nil {
+		t.Errorf("submodule still depends on another object store")
+	}
+}
+
+func TestRemoveWorktreeWithSubmodules(t *testing.T) {
+	repoDir := newRepoWithSubmodule(t)
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("with-subs")
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	wtPath := filepath.Join(gs.GetWorktreesDir(repoDir), "with-subs")
+
+	// Changes inside a submodule still protect the worktree
+	untracked := filepath.Join(wtPath, "libs", "sub", "notes.txt")
+	if err := os.WriteFile(untracked, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rc := NewRemoveCommand(gs)
+	rc.SetWorktree("with-subs")
+	if _, err := captureStdout(t, func() error { return rc.Execute(repoDir) }); err == nil {
+		t.Fatalf("expected remove to refuse a worktree with changes in a submodule")
+	}
+
+	if err := os.Remove(untracked); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := captureStdout(t, func() error { return rc.Execute(repoDir) }); err != nil {
+		t.Fatalf("remove of a clean worktree with submodules failed: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(wtPath); !os.IsNotExist(err) {
+		t.Errorf("%s still exists", wtPath)
+	}
+}
+
+func TestAddNoSubmodules(t *testing.T) {
+	repoDir := newRepoWithSubmodule(t)
+	gs := services.NewGitService("")
+
+	ac := NewAddCommand(gs)
+	ac.SetCreateBranch(true)
+	ac.SetBranchName("without-subs")
+	ac.SetNoSubmodules(true)
+	if _, err := captureStdout(t, func() error { return ac.Execute(repoDir) }); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	subPath := filepath.Join(gs.GetWorktreesDir(repoDir), "without-subs", "libs", "sub")
+	if _, err := os.Stat(filepath.Join(subPath, "README.md")); err == nil {
+		t.Errorf("submodule was initialized despite --no-submodules")
+	}
+
+	lc := NewListCommand(gs)
+	lc.SetVerbose(true)
+	output, err := captureStdout(t, func() error { return lc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if !strings.Contains(output, "1 submodule(s) uninitialized") {
+		t.Errorf("list -v does not flag uninitialized submodules:\n%s", output)
+	}
+}
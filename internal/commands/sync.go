@@ -0,0 +1,234 @@
+// Sync command implementation
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// defaultSyncJobs is how many worktrees 'wt sync' updates at the same time
+const defaultSyncJobs = 4
+
+// Outcomes of syncing a worktree
+const (
+	syncUpdated    = "updated"
+	syncUpToDate   = "up to date"
+	syncSkipped    = "skipped"
+	syncConflicted = "conflicted"
+	syncFailed     = "failed"
+)
+
+// SyncCommand handles the 'wt sync' command
+type SyncCommand struct {
+	gitService *services.GitService
+	strategy   string // ff or rebase; empty uses wt.sync.strategy
+	jobs       int
+	noFetch    bool
+}
+
+// syncResult is what happened to one worktree
+type syncResult struct {
+	worktree models.Worktree
+	target   string // Ref the worktree was updated from
+	outcome  string
+	detail   string
+}
+
+// NewSyncCommand creates a new SyncCommand instance
+func NewSyncCommand(gitService *services.GitService) *SyncCommand {
+	return &SyncCommand{
+		gitService: gitService,
+		jobs:       defaultSyncJobs,
+	}
+}
+
+// SetStrategy sets how diverged branches are updated: ff skips them, rebase rebases them
+func (sc *SyncCommand) SetStrategy(strategy string) {
+	sc.strategy = strategy
+}
+
+// SetJobs sets how many worktrees are updated at the same time
+func (sc *SyncCommand) SetJobs(jobs int) {
+	if jobs > 0 {
+		sc.jobs = jobs
+	}
+}
+
+// SetNoFetch skips fetching before updating
+func (sc *SyncCommand) SetNoFetch(noFetch bool) {
+	sc.noFetch = noFetch
+}
+
+// Execute fetches once and then updates every clean worktree from its upstream,
+// or from the default branch if it has none. It returns an error if a worktree
+// conflicted or failed.
+func (sc *SyncCommand) Execute(repoPath string) error {
+	strategy := sc.strategy
+	if strategy == "" {
+		cfg, err := sc.gitService.LoadConfig(repoPath)
+		if err != nil {
+			return err
+		}
+		strategy = cfg.SyncStrategy
+	}
+	if strategy != models.SyncFastForward && strategy != models.SyncRebase {
+		return fmt.Errorf("unknown sync strategy %q: want %s or %s", strategy, models.SyncFastForward, models.SyncRebase)
+	}
+
+	if !sc.noFetch {
+		fmt.Println("Fetching...")
+		if err := sc.gitService.FetchAll(repoPath); err != nil {
+			return err
+		}
+	}
+
+	worktrees, err := sc.gitService.GetWorktrees(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	defaultRef, _ := sc.gitService.GetDefaultRef(repoPath)
+	worktrees = slices.DeleteFunc(worktrees, func(wt models.Worktree) bool { return wt.IsBare })
+
+	// Each worktree is updated by one of sc.jobs workers
+	results := make([]syncResult, len(worktrees))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(sc.jobs, len(worktrees)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = sc.syncWorktree(worktrees[i], defaultRef, strategy)
+			}
+		}()
+	}
+	for i := range worktrees {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	fmt.Print(formatSyncResults(results))
+
+	var problems, updated int
+	for _, r := range results {
+		switch r.outcome {
+		case syncConflicted, syncFailed:
+			problems++
+		case syncUpdated:
+			updated++
+		}
+	}
+	fmt.Printf("Updated %d of %d worktree(s)\n", updated, len(results))
+	if problems > 0 {
+		return fmt.Errorf("%d worktree(s) could not be updated", problems)
+	}
+	return nil
+}
+
+// syncWorktree updates a single worktree
+func (sc *SyncCommand) syncWorktree(wt models.Worktree, defaultRef, strategy string) syncResult {
+	result := syncResult{worktree: wt}
+	skip := func(detail string) syncResult {
+		result.outcome = syncSkipped
+		result.detail = detail
+		return result
+	}
+
+	switch {
+	case wt.IsPrunable():
+		return skip("missing")
+	case wt.Branch == "":
+		return skip("detached HEAD")
+	case wt.IsOrphan:
+		return skip("no commits")
+	}
+
+	status, err := sc.gitService.GetWorktreeStatus(wt.Path)
+	if err != nil {
+		result.outcome = syncFailed
+		result.detail = err.Error()
+		return result
+	}
+	if status.Operation != "" {
+		return skip(status.Operation + " in progress")
+	}
+	if status.IsDirty() {
+		return skip("uncommitted changes")
+	}
+
+	result.target = status.Upstream
+	if result.target == "" {
+		result.target = defaultRef
+	}
+	if result.target == "" {
+		return skip("no upstream")
+	}
+
+	switch {
+	case sc.gitService.IsAncestor(wt.Path, result.target, "HEAD"):
+		result.outcome = syncUpToDate
+	case sc.gitService.IsAncestor(wt.Path, "HEAD", result.target):
+		if err := sc.gitService.FastForward(wt.Path, result.target); err != nil {
+			result.outcome = syncFailed
+			result.detail = err.Error()
+			return result
+		}
+		result.outcome = syncUpdated
+		result.detail = "fast-forwarded"
+	case strategy == models.SyncFastForward:
+		return skip("diverged; use --strategy rebase")
+	default:
+		if err := sc.gitService.Rebase(wt.Path, result.target); err != nil {
+			result.outcome = syncConflicted
+			result.detail = "rebase stopped and was aborted"
+			return result
+		}
+		result.outcome = syncUpdated
+		result.detail = "rebased"
+	}
+	return result
+}
+
+// formatSyncResults renders the results as a table
+func formatSyncResults(results []syncResult) string {
+	header := []string{"WORKTREE", "BRANCH", "FROM", "RESULT"}
+	rows := [][]string{header}
+	for _, r := range results {
+		outcome := r.outcome
+		if r.detail != "" {
+			outcome += " (" + r.detail + ")"
+		}
+		rows = append(rows, []string{r.worktree.Name, r.worktree.Branch, r.target, outcome})
+	}
+
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	var result strings.Builder
+	for _, row := range rows {
+		result.WriteString(fmt.Sprintf("%-*s  %-*s  %-*s  %s\n",
+			widths[0], row[0], widths[1], row[1], widths[2], row[2], row[3]))
+	}
+	return result.String()
+}
+
+// RunSyncCommand is the entry point for the sync command
+func RunSyncCommand(repoPath, gitPath, strategy string, jobs int, noFetch bool) error {
+	gitService := services.NewGitService(gitPath)
+	syncCmd := NewSyncCommand(gitService)
+	syncCmd.SetStrategy(strategy)
+	syncCmd.SetJobs(jobs)
+	syncCmd.SetNoFetch(noFetch)
+
+	return syncCmd.Execute(repoPath)
+}
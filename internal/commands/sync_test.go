@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// pushFromClone commits file with content on branch in a second clone of
+// remoteDir and pushes it, so that the branch moves ahead on the remote
+func pushFromClone(t *testing.T, remoteDir, branch, file, content string) {
+	t.Helper()
+	cloneDir := filepath.Join(t.TempDir(), "clone")
+	runTestGit(t, filepath.Dir(cloneDir), "clone", "-q", "-b", branch, remoteDir, cloneDir)
+	runTestGit(t, cloneDir, "config", "user.email", "other@example.com")
+	runTestGit(t, cloneDir, "config", "user.name", "Other")
+	if err := os.WriteFile(filepath.Join(cloneDir, file), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, cloneDir, "commit", "-qam", "remote change")
+	runTestGit(t, cloneDir, "push", "-q", "origin", branch)
+}
+
+func TestSync(t *testing.T) {
+	repoDir := newTestRepo(t)
+	remoteDir := addTestRemote(t, repoDir, "origin")
+	gs := services.NewGitService("")
+
+	// behind: fast-forwarded; dirty: skipped; diverged: skipped with ff, rebased with rebase;
+	// conflict: rebase aborted
+	paths := map[string]string{}
+	for _, branch := range []string{"behind", "dirty", "diverged", "conflict"} {
+		paths[branch] = addTestWorktree(t, gs, repoDir, branch)
+		if err := os.WriteFile(filepath.Join(paths[branch], branch+".txt"), []byte("base"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runTestGit(t, paths[branch], "add", ".")
+		runTestGit(t, paths[branch], "commit", "-qm", "base")
+		runTestGit(t, paths[branch], "push", "-q", "-u", "origin", branch)
+		pushFromClone(t, remoteDir, branch, branch+".txt", "remote")
+	}
+	if err := os.WriteFile(filepath.Join(paths["dirty"], "README.md"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(paths["diverged"], "local.txt"), []byte("local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, paths["diverged"], "add", ".")
+	runTestGit(t, paths["diverged"], "commit", "-qm", "local change")
+	if err := os.WriteFile(filepath.Join(paths["conflict"], "conflict.txt"), []byte("local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, paths["conflict"], "commit", "-qam", "conflicting change")
+
+	sc := NewSyncCommand(gs)
+	output, err := captureStdout(t, func() error { return sc.Execute(repoDir) })
+	if err != nil {
+		t.Fatalf("sync failed: %v\n%s", err, output)
+	}
+	for _, want := range []string{"fast-forwarded", "uncommitted changes", "diverged"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if got := runTestGit(t, paths["behind"], "show", "HEAD:behind.txt"); got != "remote" {
+		t.Errorf("behind.txt = %q, want remote", got)
+	}
+	if got := runTestGit(t, paths["dirty"], "show", "HEAD:dirty.txt"); got != "base" {
+		t.Errorf("dirty worktree was updated")
+	}
+
+	sc = NewSyncCommand(gs)
+	sc.SetStrategy("rebase")
+	sc.SetNoFetch(true)
+	output, err = captureStdout(t, func() error { return sc.Execute(repoDir) })
+	if err == nil || !strings.Contains(err.Error(), "1 worktree(s)") {
+		t.Fatalf("expected the conflicting worktree to be reported, got %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "rebased") || !strings.Contains(output, syncConflicted) {
+		t.Errorf("output missing rebase results:\n%s", output)
+	}
+	if got := runTestGit(t, paths["diverged"], "show", "HEAD~1:diverged.txt"); got != "remote" {
+		t.Errorf("diverged branch was not rebased onto its upstream")
+	}
+	if got := runTestGit(t, paths["conflict"], "show", "HEAD:conflict.txt"); got != "local" {
+		t.Errorf("conflicting branch changed: conflict.txt = %q", got)
+	}
+	if status, err := gs.GetWorktreeStatus(paths["conflict"]); err != nil || status.Operation != "" || status.IsDirty() {
+		t.Errorf("conflicting worktree was not restored: %+v, %v", status, err)
+	}
+}
@@ -0,0 +1,46 @@
+// Transaction support for commands that make several changes to a repository
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// rollbackStep undoes a single change made by a command
+type rollbackStep struct {
+	description string
+	undo        func() error
+}
+
+// transaction records the changes a command has made so that they can be
+// undone in reverse order if a later step fails
+type transaction struct {
+	steps []rollbackStep
+	out   io.Writer // Where rollback steps are reported; nil is standard output
+}
+
+// record registers how to undo a change that has just been made
+func (tx *transaction) record(description string, undo func() error) {
+	tx.steps = append(tx.steps, rollbackStep{description: description, undo: undo})
+}
+
+// rollback undoes all recorded changes, most recent first.
+// It keeps going when a step fails and returns the combined errors.
+func (tx *transaction) rollback() error {
+	out := tx.out
+	if out == nil {
+		out = os.Stdout
+	}
+	var errs []error
+	for i := len(tx.steps) - 1; i >= 0; i-- {
+		step := tx.steps[i]
+		fmt.Fprintf(out, "Rolling back: %s\n", step.description)
+		if err := step.undo(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", step.description, err))
+		}
+	}
+	tx.steps = nil
+	return errors.Join(errs...)
+}
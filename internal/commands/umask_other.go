@@ -0,0 +1,9 @@
+//go:build !unix
+
+// Process umask handling for systems without one
+package commands
+
+// restrictUmask does nothing where there is no umask
+func restrictUmask() (restore func()) {
+	return func() {}
+}
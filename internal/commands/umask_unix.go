@@ -0,0 +1,13 @@
+//go:build unix
+
+// Process umask handling for Unix systems
+package commands
+
+import "syscall"
+
+// restrictUmask makes files created until restore is called inaccessible to
+// anyone but the owner. The umask is process-wide, so keep the window short.
+func restrictUmask() (restore func()) {
+	old := syscall.Umask(0177)
+	return func() { syscall.Umask(old) }
+}
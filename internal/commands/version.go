@@ -6,10 +6,26 @@ import (
 	"os"
 
 	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
 )
 
-// RunVersionCommand executes the version command
-func RunVersionCommand(args []string, jsonOutput bool) error {
+// capabilityStatus reports whether the installed git supports a capability
+type capabilityStatus struct {
+	Name       string
+	MinVersion string
+	Available  bool
+}
+
+// verboseVersionInfo adds the detected git version and capabilities to VersionInfo
+type verboseVersionInfo struct {
+	*models.VersionInfo
+	GitVersion   string
+	Capabilities []capabilityStatus
+}
+
+// RunVersionCommand executes the version command.
+// In verbose mode it also reports the installed git and which capabilities it has.
+func RunVersionCommand(gitPath string, args []string, jsonOutput, verbose bool) error {
 	// Get version information
 	info := models.GetVersionInfo()
 
@@ -17,6 +33,10 @@ func RunVersionCommand(args []string, jsonOutput bool) error {
 		return fmt.Errorf("invalid version information: %w", err)
 	}
 
+	if verbose {
+		return outputVerboseVersion(services.NewGitService(gitPath), info, jsonOutput)
+	}
+
 	// Format and output version information
 	if jsonOutput {
 		return outputJSONVersion(info)
@@ -33,10 +53,12 @@ func PrintVersionHelp() {
 Flags:
   -h, --help   Show help for version command
   -j, --json    Output version information in JSON format
+  -v, --verbose Also show the detected git version and capabilities
 
 Examples:
   wt version                    # Display version in text format
   wt version -j                 # Display version in JSON format
+  wt version -v                 # Include git version and capabilities
 `)
 }
 
@@ -64,3 +86,43 @@ func outputJSONVersion(info *models.VersionInfo) error {
 	fmt.Println(string(output))
 	return nil
 }
+
+// outputVerboseVersion outputs version information with the git version and capabilities
+func outputVerboseVersion(gitService *services.GitService, info *models.VersionInfo, jsonOutput bool) error {
+	version, err := gitService.ParsedGitVersion()
+	if err != nil {
+		return err
+	}
+
+	verbose := verboseVersionInfo{VersionInfo: info, GitVersion: version.String()}
+	for _, c := range models.Capabilities {
+		verbose.Capabilities = append(verbose.Capabilities, capabilityStatus{
+			Name:       c.Name,
+			MinVersion: c.MinVersion.String(),
+			Available:  c.SupportedBy(version),
+		})
+	}
+
+	if jsonOutput {
+		output, err := json.MarshalIndent(verbose, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format version info as JSON: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if err := outputTextVersion(info); err != nil {
+		return err
+	}
+	fmt.Printf("git version %s\n", verbose.GitVersion)
+	fmt.Println("capabilities:")
+	for _, c := range verbose.Capabilities {
+		mark := "✓"
+		if !c.Available {
+			mark = "✗"
+		}
+		fmt.Printf("  %s %s (git >= %s)\n", mark, c.Name, c.MinVersion)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/smoerfugl/wt/internal/models"
@@ -33,7 +34,7 @@ func TestRunVersionCommand(t *testing.T) {
 			r, w, _ := os.Pipe()
 			os.Stdout = w
 
-			err := RunVersionCommand([]string{}, tt.jsonOutput)
+			err := RunVersionCommand("git", []string{}, tt.jsonOutput, false)
 
 			w.Close()
 			os.Stdout = old
@@ -104,3 +105,15 @@ func TestVersionInfoValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestRunVersionCommandVerbose(t *testing.T) {
+	output, err := captureStdout(t, func() error {
+		return RunVersionCommand("git", nil, false, true)
+	})
+	if err != nil {
+		t.Fatalf("RunVersionCommand() error = %v", err)
+	}
+	if !strings.Contains(output, "git version ") || !strings.Contains(output, models.CapAddOrphan.Name) {
+		t.Errorf("verbose output missing git version or capabilities: %q", output)
+	}
+}
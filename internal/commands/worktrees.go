@@ -0,0 +1,44 @@
+// Helpers for commands that operate on an existing worktree
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/models"
+)
+
+// FindWorktree returns the worktree that arg refers to: its path, directory name or branch
+func FindWorktree(worktrees []models.Worktree, arg string) (models.Worktree, error) {
+	if abs, err := filepath.Abs(arg); err == nil {
+		for _, wt := range worktrees {
+			if samePath(wt.Path, abs) {
+				return wt, nil
+			}
+		}
+	}
+	for _, wt := range worktrees {
+		if wt.Name == arg {
+			return wt, nil
+		}
+	}
+	for _, wt := range worktrees {
+		if wt.Branch != "" && wt.Branch == arg {
+			return wt, nil
+		}
+	}
+	return models.Worktree{}, fmt.Errorf("no worktree named %q (see 'wt list')", arg)
+}
+
+// isWithin reports whether path is dir or lies below it, resolving symlinks where possible
+func isWithin(path, dir string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
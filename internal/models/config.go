@@ -0,0 +1,33 @@
+// Config represents per-repository wt settings stored in git config
+package models
+
+import "time"
+
+// Config holds the wt.* settings of a repository.
+// Values are read from git config, so they can be set per repository
+// (git config wt.gc.olderThan 30d) or globally (git config --global ...).
+type Config struct {
+	GCOlderThan time.Duration // wt.gc.olderThan: default age for 'wt gc'
+	GCKeep      []string      // wt.gc.keep: branch patterns that 'wt gc' never collects
+	Copy        []string      // wt.copy: globs copied from the main worktree by 'wt add'
+	Link        []string      // wt.link: globs symlinked from the main worktree by 'wt add'
+
+	SyncStrategy string // wt.sync.strategy: how 'wt sync' updates diverged branches (ff or rebase)
+
+	// SparseProfiles maps a profile name to its directories (wt.sparse.<name>, repeatable)
+	SparseProfiles map[string][]string
+}
+
+// Strategies for 'wt sync'
+const (
+	SyncFastForward = "ff"     // Only fast-forward; diverged branches are skipped
+	SyncRebase      = "rebase" // Rebase diverged branches, skipping them on conflicts
+)
+
+// NewConfig creates a Config with default values
+func NewConfig() *Config {
+	return &Config{
+		SyncStrategy:   SyncFastForward,
+		SparseProfiles: map[string][]string{},
+	}
+}
@@ -0,0 +1,30 @@
+// CheckResult represents the outcome of a single 'wt doctor' check
+package models
+
+// CheckStatus is the severity of a check result
+type CheckStatus string
+
+// Check statuses, in increasing order of severity
+const (
+	CheckOK      CheckStatus = "ok"
+	CheckWarning CheckStatus = "warning"
+	CheckError   CheckStatus = "error"
+)
+
+// CheckResult represents the result of a diagnostic check
+type CheckResult struct {
+	Name    string      `json:"name"`          // Short name of the check
+	Status  CheckStatus `json:"status"`        // Outcome of the check
+	Message string      `json:"message"`       // What was found
+	Fix     string      `json:"fix,omitempty"` // Suggested remedy for warnings and errors
+}
+
+// NewCheckResult creates a new CheckResult instance
+func NewCheckResult(name string, status CheckStatus, message, fix string) CheckResult {
+	return CheckResult{
+		Name:    name,
+		Status:  status,
+		Message: message,
+		Fix:     fix,
+	}
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GitVersion is a parsed git version such as 2.39.5
+type GitVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// MinGitVersion is the oldest git version wt supports.
+// git 2.17 introduced 'git worktree move' and 'git worktree remove'.
+var MinGitVersion = GitVersion{Major: 2, Minor: 17}
+
+// ParseGitVersion parses versions such as "2.39.5", "2.45.1.windows.1" or "2.42.0-rc1"
+func ParseGitVersion(s string) (GitVersion, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "git version ")
+	if fields := strings.Fields(s); len(fields) > 0 {
+		// Drop vendor suffixes such as "(Apple Git-146)"
+		s = fields[0]
+	}
+
+	parts := strings.SplitN(s, ".", 4)
+	if len(parts) < 2 {
+		return GitVersion{}, fmt.Errorf("invalid git version %q", s)
+	}
+
+	var numbers [3]int
+	for i := 0; i < len(parts) && i < 3; i++ {
+		// Release candidates are reported as e.g. "0-rc1"
+		digits := strings.SplitN(parts[i], "-", 2)[0]
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			if i == 2 {
+				break
+			}
+			return GitVersion{}, fmt.Errorf("invalid git version %q", s)
+		}
+		numbers[i] = n
+	}
+	return GitVersion{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// Compare returns -1, 0 or 1 when v is older than, equal to or newer than other
+func (v GitVersion) Compare(other GitVersion) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether v is the same as or newer than other
+func (v GitVersion) AtLeast(other GitVersion) bool {
+	return v.Compare(other) >= 0
+}
+
+// String formats the version as major.minor.patch, leaving out a zero patch level
+func (v GitVersion) String() string {
+	if v.Patch == 0 {
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Capability is a git feature that wt only uses when the installed git supports it
+type Capability struct {
+	Name       string
+	MinVersion GitVersion
+}
+
+// Git capabilities wt relies on, with the release that introduced them
+var (
+	CapWorktreeRepair = Capability{Name: "worktree repair", MinVersion: GitVersion{Major: 2, Minor: 30}}
+	CapListExpire     = Capability{Name: "worktree list --expire", MinVersion: GitVersion{Major: 2, Minor: 31}}
+	CapListNul        = Capability{Name: "worktree list -z", MinVersion: GitVersion{Major: 2, Minor: 36}}
+	CapSparseCheckout = Capability{Name: "per-worktree sparse-checkout", MinVersion: GitVersion{Major: 2, Minor: 36}}
+	CapAddOrphan      = Capability{Name: "worktree add --orphan", MinVersion: GitVersion{Major: 2, Minor: 42}}
+)
+
+// Capabilities lists every capability, oldest first
+var Capabilities = []Capability{CapWorktreeRepair, CapListExpire, CapListNul, CapSparseCheckout, CapAddOrphan}
+
+// SupportedBy reports whether git version v has the capability
+func (c Capability) SupportedBy(v GitVersion) bool {
+	return v.AtLeast(c.MinVersion)
+}
+
+// RequiresError is returned when a capability is needed but git is too old
+func (c Capability) RequiresError(v GitVersion) error {
+	return fmt.Errorf("%s requires git >= %s (found %s)", c.Name, c.MinVersion, v)
+}
@@ -0,0 +1,63 @@
+package models
+
+import "testing"
+
+func TestParseGitVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    GitVersion
+		wantErr bool
+	}{
+		{"2.39.5", GitVersion{2, 39, 5}, false},
+		{"git version 2.17.0", GitVersion{2, 17, 0}, false},
+		{"2.45.1.windows.1", GitVersion{2, 45, 1}, false},
+		{"2.42.0-rc1", GitVersion{2, 42, 0}, false},
+		{"2.39.3 (Apple Git-146)", GitVersion{2, 39, 3}, false},
+		{"3.0", GitVersion{3, 0, 0}, false},
+		{"unknown", GitVersion{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseGitVersion(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGitVersion(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseGitVersion(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestGitVersionCompare(t *testing.T) {
+	tests := []struct {
+		version GitVersion
+		want    bool
+	}{
+		{GitVersion{2, 39, 5}, true},
+		{GitVersion{2, 17, 0}, true},
+		{GitVersion{2, 16, 9}, false},
+		{GitVersion{3, 0, 0}, true},
+		{GitVersion{1, 99, 0}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.version.AtLeast(MinGitVersion); got != tt.want {
+			t.Errorf("%v.AtLeast(%v) = %v, want %v", tt.version, MinGitVersion, got, tt.want)
+		}
+	}
+}
+
+func TestCapabilitySupportedBy(t *testing.T) {
+	v := GitVersion{2, 39, 5}
+	if !CapListNul.SupportedBy(v) {
+		t.Errorf("%s should be supported by %v", CapListNul.Name, v)
+	}
+	if CapAddOrphan.SupportedBy(v) {
+		t.Errorf("%s should not be supported by %v", CapAddOrphan.Name, v)
+	}
+	want := "worktree add --orphan requires git >= 2.42 (found 2.39.5)"
+	if err := CapAddOrphan.RequiresError(v); err == nil || err.Error() != want {
+		t.Errorf("RequiresError() = %v, want %q", err, want)
+	}
+}
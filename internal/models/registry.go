@@ -0,0 +1,9 @@
+// RegisteredRepo is an entry in the user's repository registry
+package models
+
+// RegisteredRepo is a repository known to wt by name, so that commands such as
+// 'wt list --all-repos' can reach it from anywhere
+type RegisteredRepo struct {
+	Name string `json:"name"`
+	Path string `json:"path"` // Main worktree, or the repository itself if it is bare
+}
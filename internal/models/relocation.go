@@ -0,0 +1,17 @@
+// Relocation records a 'wt relocate' in progress so that it can be resumed
+package models
+
+// Relocation is the journal of a move of every worktree from one root directory
+// to another. It is written before the first worktree is moved and removed once
+// the last one is done.
+type Relocation struct {
+	From  string           `json:"from"`
+	To    string           `json:"to"`
+	Moves []RelocationMove `json:"moves"`
+}
+
+// RelocationMove is the move of a single worktree
+type RelocationMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
@@ -0,0 +1,55 @@
+// WorktreeStatus summarizes the state of a worktree for 'wt status'
+package models
+
+import "time"
+
+// In-progress operations detected from a worktree's git directory
+const (
+	OperationRebase     = "rebase"
+	OperationMerge      = "merge"
+	OperationCherryPick = "cherry-pick"
+	OperationRevert     = "revert"
+	OperationBisect     = "bisect"
+	OperationAm         = "am"
+)
+
+// WorktreeStatus holds the working tree changes, upstream divergence, last commit
+// and in-progress operation of a worktree
+type WorktreeStatus struct {
+	Repo       string `json:"repo,omitempty"` // Registered repository name; only set with --all-repos
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Branch     string `json:"branch"`
+	Locked     bool   `json:"locked"`
+	LockReason string `json:"lock_reason,omitempty"`
+	Prunable   string `json:"prunable,omitempty"` // Why git considers the worktree prunable
+	Missing    bool   `json:"missing,omitempty"`  // The directory is gone, e.g. a locked worktree on an unplugged disk
+
+	Staged    int `json:"staged"`    // Files with staged changes
+	Modified  int `json:"modified"`  // Files with unstaged changes
+	Untracked int `json:"untracked"` // Untracked files
+	Conflicts int `json:"conflicts"` // Files with merge conflicts
+
+	Upstream string `json:"upstream,omitempty"` // Upstream branch, e.g. origin/main
+	Ahead    int    `json:"ahead"`              // Commits not on the upstream
+	Behind   int    `json:"behind"`             // Upstream commits not on the branch
+
+	LastCommitSubject string     `json:"last_commit_subject,omitempty"`
+	LastCommitTime    *time.Time `json:"last_commit_time,omitempty"` // nil on a branch without commits
+
+	Operation string `json:"operation,omitempty"` // In-progress rebase, merge, cherry-pick, revert, bisect or am
+}
+
+// IsDirty reports whether the worktree has uncommitted changes or untracked files
+func (s *WorktreeStatus) IsDirty() bool {
+	return s.Staged+s.Modified+s.Untracked+s.Conflicts > 0
+}
+
+// IsUnpushed reports whether the branch has commits its upstream lacks, or has
+// no upstream at all. Detached worktrees are never unpushed.
+func (s *WorktreeStatus) IsUnpushed() bool {
+	if s.Branch == "" || s.LastCommitTime == nil {
+		return false
+	}
+	return s.Upstream == "" || s.Ahead > 0
+}
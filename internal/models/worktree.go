@@ -10,6 +10,36 @@ type Worktree struct {
 	IsCurrent  bool   // Whether this is the current worktree
 	IsClean    bool   // Whether the worktree has no uncommitted changes
 	IsLocked   bool   // Whether the worktree is locked
+
+	PrunableReason string // Why git considers the worktree prunable; empty if it is not
+	IsDetached     bool   // Whether HEAD is detached rather than on a branch
+	IsOrphan       bool   // Whether the branch is unborn (has no commits yet)
+	IsBare         bool   // Whether this is the bare repository itself rather than a checkout
+	LockReason     string // Why the worktree is locked; empty if it is not locked or no reason was given
+
+	UninitializedSubmodules int // Submodules that have not been initialized; only set by 'wt list -v'
+}
+
+// Worktree modes as reported by GetMode
+const (
+	ModeBranch   = "branch"
+	ModeDetached = "detached"
+	ModeOrphan   = "orphan"
+	ModeBare     = "bare"
+)
+
+// WorktreeInfo is a worktree as reported to tools: the server's clients and
+// users of pkg/wt
+type WorktreeInfo struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Branch     string `json:"branch"`
+	Mode       string `json:"mode"`
+	Commit     string `json:"commit"`
+	Main       bool   `json:"main"`
+	Locked     bool   `json:"locked"`
+	LockReason string `json:"lock_reason"`
+	Prunable   string `json:"prunable,omitempty"`
 }
 
 // NewWorktree creates a new Worktree instance
@@ -25,13 +55,71 @@ func NewWorktree(name, path, branch, commitHash string, isCurrent, isClean, isLo
 	}
 }
 
+// IsPrunable reports whether git would prune the worktree's administrative files
+func (w *Worktree) IsPrunable() bool {
+	return w.PrunableReason != ""
+}
+
+// GetMode returns how the worktree's HEAD is set up: on a branch, detached or
+// on an orphan branch without commits. The entry of a bare repository is "bare".
+func (w *Worktree) GetMode() string {
+	switch {
+	case w.IsBare:
+		return ModeBare
+	case w.IsDetached:
+		return ModeDetached
+	case w.IsOrphan:
+		return ModeOrphan
+	default:
+		return ModeBranch
+	}
+}
+
+// DescribeLock returns "locked", followed by the lock reason if there is one
+func (w *Worktree) DescribeLock() string {
+	if w.LockReason != "" {
+		return "locked: " + w.LockReason
+	}
+	return "locked"
+}
+
 // GetStatus returns the status of the worktree
 func (w *Worktree) GetStatus() string {
+	if w.IsBare {
+		return "bare"
+	}
 	if w.IsLocked {
 		return "locked"
 	}
+	if w.IsPrunable() {
+		return "prunable"
+	}
 	if w.IsClean {
 		return "clean"
 	}
 	return "dirty"
 }
+
+// Info returns the worktree as reported to tools
+func (w *Worktree) Info() WorktreeInfo {
+	return WorktreeInfo{
+		Name:       w.Name,
+		Path:       w.Path,
+		Branch:     w.Branch,
+		Mode:       w.GetMode(),
+		Commit:     w.CommitHash,
+		Main:       w.IsCurrent,
+		Locked:     w.IsLocked,
+		LockReason: w.LockReason,
+		Prunable:   w.PrunableReason,
+	}
+}
+
+// Infos returns the worktrees as reported to tools; none is an empty list
+func Infos(worktrees []Worktree) []WorktreeInfo {
+	result := make([]WorktreeInfo, 0, len(worktrees))
+	for i := range worktrees {
+		result = append(result, worktrees[i].Info())
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+// Capabilities gate wt features on the installed git version
+package services
+
+import (
+	"sync"
+
+	"github.com/smoerfugl/wt/internal/models"
+)
+
+// versionCache holds the git version once it has been detected. It is safe for
+// concurrent use, as commands such as 'wt sync' share a GitService between goroutines.
+type versionCache struct {
+	mu      sync.Mutex
+	version *models.GitVersion
+}
+
+// ParsedGitVersion returns the installed git version, detecting it once per
+// GitService. A failed detection is retried on the next call.
+func (gs *GitService) ParsedGitVersion() (models.GitVersion, error) {
+	gs.version.mu.Lock()
+	defer gs.version.mu.Unlock()
+	if gs.version.version != nil {
+		return *gs.version.version, nil
+	}
+	raw, err := gs.GetGitVersion()
+	if err != nil {
+		return models.GitVersion{}, err
+	}
+	version, err := models.ParseGitVersion(raw)
+	if err != nil {
+		return models.GitVersion{}, err
+	}
+	gs.version.version = &version
+	return version, nil
+}
+
+// HasCapability reports whether the installed git supports the capability.
+// If the version cannot be detected the capability is assumed to be missing.
+func (gs *GitService) HasCapability(c models.Capability) bool {
+	version, err := gs.ParsedGitVersion()
+	return err == nil && c.SupportedBy(version)
+}
+
+// RequireCapability returns a "requires git >= X" error if the installed git lacks the capability
+func (gs *GitService) RequireCapability(c models.Capability) error {
+	version, err := gs.ParsedGitVersion()
+	if err != nil {
+		return err
+	}
+	if !c.SupportedBy(version) {
+		return c.RequiresError(version)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Config loading for wt.* keys stored in git config
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/utils"
+)
+
+// LoadConfig reads the wt.* settings that apply to the repository at repoPath
+func (gs *GitService) LoadConfig(repoPath string) (*models.Config, error) {
+	cmd := gs.command("config", "--get-regexp", `^wt\.`)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		// git config exits with status 1 when no key matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return models.NewConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return parseConfigOutput(output)
+}
+
+// parseConfigOutput parses the output of 'git config --get-regexp'.
+// Each line holds a lower-cased key followed by its value.
+func parseConfigOutput(output []byte) (*models.Config, error) {
+	cfg := models.NewConfig()
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "wt.gc.olderthan":
+			age, err := utils.ParseAge(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid wt.gc.olderThan: %w", err)
+			}
+			cfg.GCOlderThan = age
+		case "wt.gc.keep":
+			cfg.GCKeep = append(cfg.GCKeep, value)
+		case "wt.sync.strategy":
+			if value != models.SyncFastForward && value != models.SyncRebase {
+				return nil, fmt.Errorf("invalid wt.sync.strategy %q: want %s or %s", value, models.SyncFastForward, models.SyncRebase)
+			}
+			cfg.SyncStrategy = value
+		case "wt.copy":
+			cfg.Copy = append(cfg.Copy, value)
+		case "wt.link":
+			cfg.Link = append(cfg.Link, value)
+		default:
+			if name, ok := strings.CutPrefix(key, "wt.sparse."); ok && name != "" {
+				cfg.SparseProfiles[name] = append(cfg.SparseProfiles[name], value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseConfigOutput(t *testing.T) {
+	output := []byte("wt.gc.olderthan 30d\nwt.gc.keep release/*\nwt.gc.keep main\n" +
+		"wt.copy .env*\nwt.link node_modules/.cache\nwt.unknown value\n")
+
+	cfg, err := parseConfigOutput(output)
+	if err != nil {
+		t.Fatalf("parseConfigOutput failed: %v", err)
+	}
+	if cfg.GCOlderThan != 30*24*time.Hour {
+		t.Errorf("GCOlderThan = %v, want 720h", cfg.GCOlderThan)
+	}
+	if len(cfg.GCKeep) != 2 || cfg.GCKeep[0] != "release/*" || cfg.GCKeep[1] != "main" {
+		t.Errorf("GCKeep = %v, want [release/* main]", cfg.GCKeep)
+	}
+	if len(cfg.Copy) != 1 || cfg.Copy[0] != ".env*" {
+		t.Errorf("Copy = %v, want [.env*]", cfg.Copy)
+	}
+	if len(cfg.Link) != 1 || cfg.Link[0] != "node_modules/.cache" {
+		t.Errorf("Link = %v, want [node_modules/.cache]", cfg.Link)
+	}
+}
+
+func TestParseConfigOutputInvalidAge(t *testing.T) {
+	if _, err := parseConfigOutput([]byte("wt.gc.olderthan someday\n")); err == nil {
+		t.Fatalf("expected error for invalid wt.gc.olderThan")
+	}
+}
+
+func TestParseConfigOutputSparseProfiles(t *testing.T) {
+	output := []byte("wt.sparse.backend services/api\nwt.sparse.backend services/worker\nwt.sparse.web services/web\n")
+
+	cfg, err := parseConfigOutput(output)
+	if err != nil {
+		t.Fatalf("parseConfigOutput failed: %v", err)
+	}
+	if got := cfg.SparseProfiles["backend"]; len(got) != 2 || got[1] != "services/worker" {
+		t.Errorf("backend profile = %v, want [services/api services/worker]", got)
+	}
+	if got := cfg.SparseProfiles["web"]; len(got) != 1 {
+		t.Errorf("web profile = %v, want [services/web]", got)
+	}
+}
+
+func TestParseConfigOutputSyncStrategy(t *testing.T) {
+	cfg, err := parseConfigOutput([]byte("wt.sync.strategy rebase\n"))
+	if err != nil {
+		t.Fatalf("parseConfigOutput failed: %v", err)
+	}
+	if cfg.SyncStrategy != "rebase" {
+		t.Errorf("SyncStrategy = %q, want rebase", cfg.SyncStrategy)
+	}
+	if _, err := parseConfigOutput([]byte("wt.sync.strategy merge\n")); err == nil {
+		t.Errorf("expected error for unknown wt.sync.strategy")
+	}
+}
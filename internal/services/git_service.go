@@ -3,18 +3,40 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/utils"
 )
 
 // GitService handles Git operations
 type GitService struct {
 	gitPath string
+	version *versionCache   // Shared with the copies made by WithContext
+	ctx     context.Context // Kills running git processes when done; nil never does
+}
+
+// GitError is a git command that failed, with what it wrote to standard error
+type GitError struct {
+	Args   []string
+	Output string
+	Err    error
+}
+
+func (e *GitError) Error() string {
+	return fmt.Sprintf("git %s: %v (output: %s)", strings.Join(e.Args, " "), e.Err, e.Output)
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
 }
 
 // NewGitService creates a new GitService instance
@@ -22,15 +44,66 @@ func NewGitService(gitPath string) *GitService {
 	if gitPath == "" {
 		gitPath = "git"
 	}
-	return &GitService{gitPath: gitPath}
+	return &GitService{gitPath: gitPath, version: &versionCache{}}
+}
+
+// WithContext returns a copy of gs whose git processes are killed when ctx is done
+func (gs *GitService) WithContext(ctx context.Context) *GitService {
+	withCtx := *gs
+	withCtx.ctx = ctx
+	return &withCtx
+}
+
+// command returns a git command that is bound to the service's context
+func (gs *GitService) command(args ...string) *exec.Cmd {
+	if gs.ctx == nil {
+		return exec.Command(gs.gitPath, args...)
+	}
+	return exec.CommandContext(gs.ctx, gs.gitPath, args...)
+}
+
+// runGit runs git in dir and returns its standard output without the trailing newline
+func (gs *GitService) runGit(dir string, args ...string) (string, error) {
+	cmd := gs.command(args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", &GitError{Args: args, Output: strings.TrimSpace(stderr.String()), Err: err}
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// runGitCombined runs git in dir and returns its standard output and error combined
+func (gs *GitService) runGitCombined(dir string, args ...string) ([]byte, error) {
+	cmd := gs.command(args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return output, &GitError{Args: args, Output: strings.TrimSpace(string(output)), Err: err}
+	}
+	return output, nil
 }
 
 // GetWorktrees retrieves all worktrees from the repository
 func (gs *GitService) GetWorktrees(repoPath string) ([]models.Worktree, error) {
-	cmd := exec.Command(gs.gitPath, "worktree", "list", "--porcelain")
-	cmd.Dir = repoPath
+	return gs.GetWorktreesWithExpire(repoPath, "")
+}
 
-	output, err := cmd.CombinedOutput()
+// GetWorktreesWithExpire retrieves all worktrees, only marking missing worktrees
+// as prunable when they are older than expire (e.g. "2.weeks.ago").
+// An empty expire uses git's default.
+func (gs *GitService) GetWorktreesWithExpire(repoPath, expire string) ([]models.Worktree, error) {
+	args := []string{"worktree", "list", "--porcelain"}
+	if gs.HasCapability(models.CapListNul) {
+		// NUL-terminated output is safe for paths and lock reasons containing newlines
+		args = append(args, "-z")
+	}
+	if expire != "" && gs.HasCapability(models.CapListExpire) {
+		args = append(args, "--expire", expire)
+	}
+	output, err := gs.runGitCombined(repoPath, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get worktrees: %w", err)
 	}
@@ -38,20 +111,26 @@ func (gs *GitService) GetWorktrees(repoPath string) ([]models.Worktree, error) {
 	return gs.parseWorktreeOutput(output)
 }
 
-// parseWorktreeOutput parses the output from 'git worktree list --porcelain'
+// parseWorktreeOutput parses the output from 'git worktree list --porcelain',
+// with or without -z
 func (gs *GitService) parseWorktreeOutput(output []byte) ([]models.Worktree, error) {
 	var worktrees []models.Worktree
 	var currentWorktree models.Worktree
 
-	lines := bytes.Split(output, []byte("\n"))
+	separator := []byte("\n")
+	if bytes.IndexByte(output, 0) >= 0 {
+		separator = []byte{0}
+	}
+	lines := bytes.Split(output, separator)
 	for _, line := range lines {
 		if len(line) == 0 {
 			continue
 		}
 
-		// Git porcelain format uses space-separated key-value pairs
+		// Git porcelain format uses space-separated key-value pairs;
+		// attributes such as "bare", "detached" and "locked" may have no value
 		parts := bytes.Fields(line)
-		if len(parts) < 2 {
+		if len(parts) == 0 {
 			continue
 		}
 
@@ -71,6 +150,9 @@ func (gs *GitService) parseWorktreeOutput(output []byte) ([]models.Worktree, err
 			if strings.HasPrefix(value, "refs/heads/") {
 				currentWorktree.Branch = strings.TrimPrefix(value, "refs/heads/")
 				currentWorktree.CommitHash = "" // Clear commit hash if we have a branch
+			} else if strings.Trim(value, "0") == "" {
+				// An all-zero hash means the branch has no commits yet
+				currentWorktree.IsOrphan = true
 			} else if len(value) >= 7 {
 				currentWorktree.CommitHash = value[:7] // Short hash
 			}
@@ -82,11 +164,14 @@ func (gs *GitService) parseWorktreeOutput(output []byte) ([]models.Worktree, err
 				currentWorktree.Branch = value
 			}
 		case "bare":
-			// Ignore bare indicator
+			currentWorktree.IsBare = true
 		case "detached":
-			// Handle detached HEAD
+			currentWorktree.IsDetached = true
 		case "locked":
 			currentWorktree.IsLocked = true
+			currentWorktree.LockReason = value
+		case "prunable":
+			currentWorktree.PrunableReason = value
 		}
 	}
 
@@ -106,7 +191,7 @@ func (gs *GitService) parseWorktreeOutput(output []byte) ([]models.Worktree, err
 
 // GetGitVersion retrieves the Git version
 func (gs *GitService) GetGitVersion() (string, error) {
-	cmd := exec.Command(gs.gitPath, "--version")
+	cmd := gs.command("--version")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get git version: %w", err)
@@ -122,57 +207,78 @@ func (gs *GitService) GetGitVersion() (string, error) {
 }
 
 // AddWorktree creates a new worktree at the specified path for the given ref
-func (gs *GitService) AddWorktree(worktreePath, ref string) error {
-	cmd := exec.Command(gs.gitPath, "worktree", "add", worktreePath, ref)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to add worktree: %w (output: %s)", err, string(output))
+func (gs *GitService) AddWorktree(repoPath, worktreePath, ref string, noCheckout bool) error {
+	if _, err := gs.runGitCombined(repoPath, worktreeAddArgs(noCheckout, worktreePath, ref)...); err != nil {
+		return fmt.Errorf("failed to add worktree: %w", err)
 	}
 	return nil
 }
 
 // AddWorktreeWithBranch creates a new worktree with a new branch
-func (gs *GitService) AddWorktreeWithBranch(worktreePath, branchName, startPoint string) error {
-	args := []string{"worktree", "add", "-b", branchName, worktreePath}
+func (gs *GitService) AddWorktreeWithBranch(repoPath, worktreePath, branchName, startPoint string, noCheckout bool) error {
+	args := worktreeAddArgs(noCheckout, "-b", branchName, worktreePath)
 	if startPoint != "" {
 		args = append(args, startPoint)
 	}
 
-	cmd := exec.Command(gs.gitPath, args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to add worktree with branch: %w (output: %s)", err, string(output))
+	if _, err := gs.runGitCombined(repoPath, args...); err != nil {
+		return fmt.Errorf("failed to add worktree with branch: %w", err)
 	}
 	return nil
 }
 
+// worktreeAddArgs builds a 'git worktree add' command line. With noCheckout the
+// worktree is registered but left empty, e.g. so that sparse-checkout can be set up first.
+func worktreeAddArgs(noCheckout bool, args ...string) []string {
+	cmdArgs := []string{"worktree", "add"}
+	if noCheckout {
+		cmdArgs = append(cmdArgs, "--no-checkout")
+	}
+	return append(cmdArgs, args...)
+}
+
 // SetUpstreamBranch configures the upstream tracking branch for the given branch.
-// It runs: git branch --set-upstream-to=origin/<branchName> <branchName>
-// in the provided working directory.
-// If origin/<branchName> does not exist yet (e.g. the branch has not been pushed),
+// It runs: git branch --set-upstream-to=<push-remote>/<branchName> <branchName>
+// in the provided working directory, where the push remote is resolved by GetPushRemote.
+// If <push-remote>/<branchName> does not exist yet (e.g. the branch has not been pushed),
 // the function returns nil without making any changes.
 func (gs *GitService) SetUpstreamBranch(workDir, branchName string) error {
-	upstream := "origin/" + branchName
+	upstream := gs.GetPushRemote(workDir) + "/" + branchName
 	// Skip silently if the remote tracking branch does not exist yet.
-	checkCmd := exec.Command(gs.gitPath, "rev-parse", "--verify", upstream)
+	checkCmd := gs.command("rev-parse", "--verify", upstream)
 	checkCmd.Dir = workDir
 	if err := checkCmd.Run(); err != nil {
 		return nil
 	}
-	cmd := exec.Command(gs.gitPath, "branch", "--set-upstream-to="+upstream, branchName)
-	cmd.Dir = workDir
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to set upstream branch: %w (output: %s)", err, string(output))
+	if _, err := gs.runGitCombined(workDir, "branch", "--set-upstream-to="+upstream, branchName); err != nil {
+		return fmt.Errorf("failed to set upstream branch: %w", err)
 	}
 	return nil
 }
 
-// EnsureWorktreesDir ensures the worktrees directory exists
-func (gs *GitService) EnsureWorktreesDir(repoPath string) error {
+// GetWorktreesDir returns the directory new worktrees are placed in: wt.root if
+// it is set, the directory holding a bare repository (such as the hub created by
+// 'wt clone'), otherwise ../worktrees/<repo-name> relative to the repository root
+func (gs *GitService) GetWorktreesDir(repoPath string) string {
+	if root := gs.GetConfigValue(repoPath, "wt.root"); root != "" {
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(repoPath, root)
+		}
+		return filepath.Clean(root)
+	}
+	if gs.IsBareRepository(repoPath) {
+		if commonDir, err := gs.GetGitCommonDir(repoPath); err == nil {
+			return filepath.Dir(commonDir)
+		}
+	}
 	parentDir := filepath.Dir(repoPath)
 	repoName := filepath.Base(repoPath)
-	worktreesDir := filepath.Join(parentDir, "worktrees", repoName)
+	return filepath.Join(parentDir, "worktrees", repoName)
+}
+
+// EnsureWorktreesDir ensures the worktrees directory exists
+func (gs *GitService) EnsureWorktreesDir(repoPath string) error {
+	worktreesDir := gs.GetWorktreesDir(repoPath)
 
 	// Create worktrees directory if it doesn't exist
 	if err := os.MkdirAll(worktreesDir, 0755); err != nil {
@@ -181,23 +287,26 @@ func (gs *GitService) EnsureWorktreesDir(repoPath string) error {
 	return nil
 }
 
-// GetDefaultRef gets the repository's default branch
+// GetDefaultRef gets the repository's default branch on the base remote
+// (see GetBaseRemote), e.g. "origin/main" or "upstream/main"
 func (gs *GitService) GetDefaultRef(repoPath string) (string, error) {
-	// Try: git symbolic-ref refs/remotes/origin/HEAD
-	cmd := exec.Command(gs.gitPath, "symbolic-ref", "refs/remotes/origin/HEAD")
+	remote := gs.GetBaseRemote(repoPath)
+
+	// Try: git symbolic-ref refs/remotes/<remote>/HEAD
+	cmd := gs.command("symbolic-ref", "refs/remotes/"+remote+"/HEAD")
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err == nil {
 		ref := strings.TrimSpace(string(output))
-		// refs/remotes/origin/HEAD -> refs/remotes/origin/main => use origin/main
+		// refs/remotes/<remote>/HEAD -> refs/remotes/<remote>/main => use <remote>/main
 		if strings.HasPrefix(ref, "refs/remotes/") {
 			return strings.TrimPrefix(ref, "refs/remotes/"), nil
 		}
 		return ref, nil
 	}
 
-	// As a fallback, try `git remote show origin` and parse "HEAD branch: <name>"
-	cmd2 := exec.Command(gs.gitPath, "remote", "show", "origin")
+	// As a fallback, try `git remote show <remote>` and parse "HEAD branch: <name>"
+	cmd2 := gs.command("remote", "show", remote)
 	cmd2.Dir = repoPath
 	output2, err2 := cmd2.Output()
 	if err2 == nil {
@@ -208,15 +317,15 @@ func (gs *GitService) GetDefaultRef(repoPath string) (string, error) {
 				parts := strings.SplitN(line, ":", 2)
 				if len(parts) == 2 {
 					branch := strings.TrimSpace(parts[1])
-					// prefer origin/<branch>
-					return "origin/" + branch, nil
+					// prefer <remote>/<branch>
+					return remote + "/" + branch, nil
 				}
 			}
 		}
 	}
 
 	// Try to get current branch
-	cmd3 := exec.Command(gs.gitPath, "symbolic-ref", "--short", "HEAD")
+	cmd3 := gs.command("symbolic-ref", "--short", "HEAD")
 	cmd3.Dir = repoPath
 	output3, err3 := cmd3.Output()
 	if err3 == nil {
@@ -226,7 +335,7 @@ func (gs *GitService) GetDefaultRef(repoPath string) (string, error) {
 
 	// Last resort: use 'main' or 'master' if they exist
 	for _, cand := range []string{"main", "master"} {
-		cmd := exec.Command(gs.gitPath, "show-ref", "--verify", "refs/heads/"+cand)
+		cmd := gs.command("show-ref", "--verify", "refs/heads/"+cand)
 		cmd.Dir = repoPath
 		if err := cmd.Run(); err == nil {
 			return cand, nil
@@ -235,3 +344,478 @@ func (gs *GitService) GetDefaultRef(repoPath string) (string, error) {
 
 	return "", fmt.Errorf("could not determine default branch")
 }
+
+// GetLastCommitTime returns the committer date of HEAD in the given worktree
+func (gs *GitService) GetLastCommitTime(worktreePath string) (time.Time, error) {
+	output, err := gs.runGit(worktreePath, "log", "-1", "--format=%ct")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last commit time: %w", err)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit time %q: %w", output, err)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// GetLastModified returns the latest modification time of the files and
+// directories in the worktree, at any depth, and of its index. The .git entries
+// of the worktree and its submodules are skipped.
+func (gs *GitService) GetLastModified(worktreePath string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(worktreePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == worktreePath {
+				return err
+			}
+			// An entry removed while walking, or an unreadable directory
+			return nil
+		}
+		if entry.Name() == ".git" && path != worktreePath {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read worktree: %w", err)
+	}
+
+	if indexPath, err := gs.runGit(worktreePath, "rev-parse", "--git-path", "index"); err == nil {
+		if !filepath.IsAbs(indexPath) {
+			indexPath = filepath.Join(worktreePath, indexPath)
+		}
+		if indexInfo, err := os.Stat(indexPath); err == nil && indexInfo.ModTime().After(latest) {
+			latest = indexInfo.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// IsWorktreeClean reports whether the worktree has no staged, unstaged or untracked changes
+func (gs *GitService) IsWorktreeClean(worktreePath string) (bool, error) {
+	output, err := gs.runGit(worktreePath, "status", "--porcelain")
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	return strings.TrimSpace(output) == "", nil
+}
+
+// RemoveWorktree removes the worktree at worktreePath.
+// Without force, worktrees with local changes are refused.
+func (gs *GitService) RemoveWorktree(repoPath, worktreePath string, force bool) error {
+	args := []string{"worktree", "remove"}
+	if !force && gs.HasInitializedSubmodules(worktreePath) {
+		// git refuses to remove worktrees with submodules unless forced, so check
+		// for changes, including those inside the submodules, here instead
+		clean, err := gs.isWorktreeCleanWithSubmodules(worktreePath)
+		if err != nil {
+			return err
+		}
+		if !clean {
+			return fmt.Errorf("failed to remove worktree: %s contains modified or untracked files, use --force to delete it", worktreePath)
+		}
+		force = true
+	}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, worktreePath)
+	if _, err := gs.runGit(repoPath, args...); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+	return nil
+}
+
+// PruneWorktrees removes administrative files of worktrees whose directories are gone.
+// An empty expire uses git's default.
+func (gs *GitService) PruneWorktrees(repoPath, expire string) error {
+	args := []string{"worktree", "prune"}
+	if expire != "" {
+		args = append(args, "--expire", expire)
+	}
+	if _, err := gs.runGit(repoPath, args...); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+	return nil
+}
+
+// RepairWorktree reconnects a worktree that was moved by hand at worktreePath
+// with its administrative files in the repository
+func (gs *GitService) RepairWorktree(repoPath, worktreePath string) error {
+	if err := gs.RequireCapability(models.CapWorktreeRepair); err != nil {
+		return err
+	}
+	if _, err := gs.runGit(repoPath, "worktree", "repair", worktreePath); err != nil {
+		return fmt.Errorf("failed to repair worktree: %w", err)
+	}
+	return nil
+}
+
+// GetGitCommonDir returns the absolute path of the repository's common git directory
+func (gs *GitService) GetGitCommonDir(repoPath string) (string, error) {
+	dir, err := gs.runGit(repoPath, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to get git directory: %w", err)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoPath, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// GetRemotes returns the names of the repository's configured remotes
+func (gs *GitService) GetRemotes(repoPath string) ([]string, error) {
+	output, err := gs.runGit(repoPath, "remote")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remotes: %w", err)
+	}
+	return strings.Fields(output), nil
+}
+
+// GetRemoteHead returns the branch refs/remotes/<remote>/HEAD points to (e.g. "origin/main")
+func (gs *GitService) GetRemoteHead(repoPath, remote string) (string, error) {
+	output, err := gs.runGit(repoPath, "symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return "", fmt.Errorf("%s/HEAD is not set: %w", remote, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// BranchExists reports whether a local branch with the given name exists
+func (gs *GitService) BranchExists(repoPath, branchName string) bool {
+	_, err := gs.runGit(repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branchName)
+	return err == nil
+}
+
+// CheckBranchName verifies that name is a valid branch name according to
+// git's ref-format rules (no spaces, "..", trailing ".lock" and so on)
+func (gs *GitService) CheckBranchName(repoPath, name string) error {
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid branch name %q: must not start with '-'", name)
+	}
+	if _, err := gs.runGit(repoPath, "check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	return nil
+}
+
+// DeleteBranch force-deletes a local branch
+func (gs *GitService) DeleteBranch(repoPath, branchName string) error {
+	if _, err := gs.runGit(repoPath, "branch", "-D", branchName); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
+	}
+	return nil
+}
+
+// RenameBranch renames a local branch. git moves its config, such as the upstream, along with it.
+func (gs *GitService) RenameBranch(repoPath, oldName, newName string) error {
+	if _, err := gs.runGit(repoPath, "branch", "-m", oldName, newName); err != nil {
+		return fmt.Errorf("failed to rename branch: %w", err)
+	}
+	return nil
+}
+
+// GetUpstream returns the upstream of a local branch, such as origin/main, or "" if it has none
+func (gs *GitService) GetUpstream(repoPath, branchName string) string {
+	upstream, err := gs.runGit(repoPath, "for-each-ref", "--format=%(upstream:short)", "refs/heads/"+branchName)
+	if err != nil {
+		return ""
+	}
+	return upstream
+}
+
+// SetUpstream sets the upstream of a local branch to a remote-tracking branch such as origin/main
+func (gs *GitService) SetUpstream(repoPath, branchName, upstream string) error {
+	if _, err := gs.runGit(repoPath, "branch", "--set-upstream-to="+upstream, branchName); err != nil {
+		return fmt.Errorf("failed to set upstream branch: %w", err)
+	}
+	return nil
+}
+
+// UnsetUpstream removes the upstream configuration of a local branch
+func (gs *GitService) UnsetUpstream(repoPath, branchName string) error {
+	if _, err := gs.runGit(repoPath, "branch", "--unset-upstream", branchName); err != nil {
+		return fmt.Errorf("failed to unset upstream: %w", err)
+	}
+	return nil
+}
+
+// MoveWorktree moves a worktree to newPath with 'git worktree move'. git refuses
+// to move worktrees whose submodules are checked out, so those are copied instead.
+func (gs *GitService) MoveWorktree(repoPath, worktreePath, newPath string) error {
+	if gs.HasInitializedSubmodules(worktreePath) {
+		return gs.CopyWorktree(repoPath, worktreePath, newPath)
+	}
+	if _, err := gs.runGit(repoPath, "worktree", "move", worktreePath, newPath); err != nil {
+		return fmt.Errorf("failed to move worktree: %w", err)
+	}
+	return nil
+}
+
+// CopyWorktree moves a worktree to newPath by copying its files, repairing its
+// links with the repository and its submodules, then removing the original. It
+// works where 'git worktree move' does not, such as across file systems.
+func (gs *GitService) CopyWorktree(repoPath, worktreePath, newPath string) error {
+	if err := gs.RequireCapability(models.CapWorktreeRepair); err != nil {
+		return err
+	}
+	result, err := utils.CopyNoClobber(worktreePath, newPath)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", worktreePath, newPath, err)
+	}
+	if len(result.Unsupported) > 0 {
+		// Removing the original would lose them, so keep it and drop the copy
+		if err := os.RemoveAll(newPath); err != nil {
+			return fmt.Errorf("failed to remove partial copy %s: %w", newPath, err)
+		}
+		return fmt.Errorf("cannot copy %s: it contains sockets, FIFOs or devices, which would be lost; remove them and try again:\n  %s",
+			worktreePath, strings.Join(result.Unsupported, "\n  "))
+	}
+	if err := gs.RepairWorktree(repoPath, newPath); err != nil {
+		return err
+	}
+	if err := gs.ReconnectSubmodules(newPath); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(worktreePath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", worktreePath, err)
+	}
+	return nil
+}
+
+// GetTopLevel returns the root of the worktree that contains dir. Outside a
+// worktree of a bare repository, such as in a hub, it returns the repository itself.
+func (gs *GitService) GetTopLevel(dir string) (string, error) {
+	top, err := gs.runGit(dir, "rev-parse", "--show-toplevel")
+	if err == nil {
+		return top, nil
+	}
+	bare, bareErr := gs.runGit(dir, "rev-parse", "--is-bare-repository", "--absolute-git-dir")
+	if bareErr != nil {
+		return "", err
+	}
+	if lines := strings.Split(bare, "\n"); len(lines) == 2 && lines[0] == "true" {
+		return lines[1], nil
+	}
+	return "", err
+}
+
+// GetMainWorktree returns the path of the repository's main worktree
+func (gs *GitService) GetMainWorktree(repoPath string) (string, error) {
+	worktrees, err := gs.GetWorktrees(repoPath)
+	if err != nil {
+		return "", err
+	}
+	if len(worktrees) == 0 {
+		return "", fmt.Errorf("repository has no worktrees")
+	}
+	// git always lists the main worktree first
+	return worktrees[0].Path, nil
+}
+
+// StashPush stashes the staged and unstaged changes of the worktree at dir, and
+// untracked files too if includeUntracked is set. It returns the commit hash of
+// the new stash entry, or "" if there was nothing to stash.
+func (gs *GitService) StashPush(dir, message string, includeUntracked bool) (string, error) {
+	before, _ := gs.runGit(dir, "rev-parse", "--quiet", "--verify", "refs/stash")
+
+	args := []string{"stash", "push", "-m", message}
+	if includeUntracked {
+		args = append(args, "--include-untracked")
+	}
+	if _, err := gs.runGit(dir, args...); err != nil {
+		return "", fmt.Errorf("failed to stash changes: %w", err)
+	}
+
+	after, _ := gs.runGit(dir, "rev-parse", "--quiet", "--verify", "refs/stash")
+	if after == before {
+		return "", nil
+	}
+	return after, nil
+}
+
+// StashApply applies the stash entry with the given commit hash to the worktree
+// at dir, restoring which changes were staged
+func (gs *GitService) StashApply(dir, stash string) error {
+	if _, err := gs.runGit(dir, "stash", "apply", "--index", stash); err != nil {
+		return fmt.Errorf("failed to apply stashed changes: %w", err)
+	}
+	return nil
+}
+
+// StashDrop removes the stash entry with the given commit hash from the stash list
+func (gs *GitService) StashDrop(dir, stash string) error {
+	output, err := gs.runGit(dir, "stash", "list", "--format=%H %gd")
+	if err != nil {
+		return fmt.Errorf("failed to list stashes: %w", err)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		hash, ref, ok := strings.Cut(line, " ")
+		if ok && hash == stash {
+			if _, err := gs.runGit(dir, "stash", "drop", ref); err != nil {
+				return fmt.Errorf("failed to drop stash: %w", err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("stash %s not found", stash)
+}
+
+// FindRemoteBranches returns the remotes that have a remote-tracking branch
+// named branchName (e.g. refs/remotes/origin/<branchName>)
+func (gs *GitService) FindRemoteBranches(repoPath, branchName string) ([]string, error) {
+	remotes, err := gs.GetRemotes(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	var found []string
+	for _, remote := range remotes {
+		if _, err := gs.runGit(repoPath, "rev-parse", "--verify", "--quiet", "refs/remotes/"+remote+"/"+branchName); err == nil {
+			found = append(found, remote)
+		}
+	}
+	return found, nil
+}
+
+// AddWorktreeTracking creates a new worktree with a new local branch that
+// tracks the remote-tracking branch remote/branchName
+func (gs *GitService) AddWorktreeTracking(repoPath, worktreePath, branchName, remote string, noCheckout bool) error {
+	_, err := gs.runGit(repoPath, worktreeAddArgs(noCheckout, "--track", "-b", branchName, worktreePath, remote+"/"+branchName)...)
+	if err != nil {
+		return fmt.Errorf("failed to add worktree tracking %s/%s: %w", remote, branchName, err)
+	}
+	return nil
+}
+
+// ResolveRef returns the commit hash ref points to
+func (gs *GitService) ResolveRef(repoPath, ref string) (string, error) {
+	hash, err := gs.runGit(repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown ref %s", ref)
+	}
+	return strings.TrimSpace(hash), nil
+}
+
+// IsAncestor reports whether commit ancestor is reachable from commit descendant
+func (gs *GitService) IsAncestor(repoPath, ancestor, descendant string) bool {
+	_, err := gs.runGit(repoPath, "merge-base", "--is-ancestor", ancestor, descendant)
+	return err == nil
+}
+
+// DeleteRef deletes a ref such as refs/wt/pr/123
+func (gs *GitService) DeleteRef(repoPath, ref string) error {
+	if _, err := gs.runGit(repoPath, "update-ref", "-d", ref); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", ref, err)
+	}
+	return nil
+}
+
+// UpdateRef points ref at commit, creating it if needed
+func (gs *GitService) UpdateRef(repoPath, ref, commit string) error {
+	if _, err := gs.runGit(repoPath, "update-ref", ref, commit); err != nil {
+		return fmt.Errorf("failed to update %s: %w", ref, err)
+	}
+	return nil
+}
+
+// FastForward moves the branch checked out in worktreePath forward to ref.
+// It fails if the branch has diverged from ref.
+func (gs *GitService) FastForward(worktreePath, ref string) error {
+	if _, err := gs.runGit(worktreePath, "merge", "--ff-only", "--quiet", ref); err != nil {
+		return fmt.Errorf("failed to fast-forward to %s: %w", ref, err)
+	}
+	return nil
+}
+
+// ResetHard points the branch checked out in worktreePath at ref and updates the files
+func (gs *GitService) ResetHard(worktreePath, ref string) error {
+	if _, err := gs.runGit(worktreePath, "reset", "--hard", "--quiet", ref); err != nil {
+		return fmt.Errorf("failed to reset to %s: %w", ref, err)
+	}
+	return nil
+}
+
+// Rebase rebases the branch checked out in worktreePath onto ref. If the rebase
+// stops, e.g. on a conflict, it is aborted so the worktree is left as it was.
+func (gs *GitService) Rebase(worktreePath, ref string) error {
+	if _, err := gs.runGit(worktreePath, "rebase", "--quiet", ref); err != nil {
+		if _, abortErr := gs.runGit(worktreePath, "rebase", "--abort"); abortErr != nil {
+			return fmt.Errorf("failed to rebase onto %s: %w (abort failed: %v)", ref, err, abortErr)
+		}
+		return fmt.Errorf("failed to rebase onto %s: %w", ref, err)
+	}
+	return nil
+}
+
+// FetchAll fetches every remote of the repository, pruning deleted branches
+func (gs *GitService) FetchAll(repoPath string) error {
+	if _, err := gs.runGit(repoPath, "fetch", "--all", "--prune", "--quiet"); err != nil {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+	return nil
+}
+
+// AddDetachedWorktree creates a new worktree with a detached HEAD at commit
+func (gs *GitService) AddDetachedWorktree(repoPath, worktreePath, commit string, noCheckout bool) error {
+	if _, err := gs.runGit(repoPath, worktreeAddArgs(noCheckout, "--detach", worktreePath, commit)...); err != nil {
+		return fmt.Errorf("failed to add detached worktree: %w", err)
+	}
+	return nil
+}
+
+// AddOrphanWorktree creates a new worktree with an empty index on a new unborn
+// branch. Git older than 2.42 lacks 'worktree add --orphan', so there an empty
+// detached worktree is created and its HEAD pointed at the unborn branch.
+func (gs *GitService) AddOrphanWorktree(repoPath, worktreePath, branchName string) error {
+	if gs.HasCapability(models.CapAddOrphan) {
+		if _, err := gs.runGit(repoPath, "worktree", "add", "--orphan", "-b", branchName, worktreePath); err != nil {
+			return fmt.Errorf("failed to add orphan worktree: %w", err)
+		}
+		return nil
+	}
+
+	if gs.BranchExists(repoPath, branchName) {
+		return fmt.Errorf("failed to add orphan worktree: branch '%s' already exists", branchName)
+	}
+	if _, err := gs.runGit(repoPath, "worktree", "add", "--detach", "--no-checkout", worktreePath); err != nil {
+		return fmt.Errorf("failed to add orphan worktree: %w", err)
+	}
+	for _, args := range [][]string{
+		{"symbolic-ref", "HEAD", "refs/heads/" + branchName},
+		{"read-tree", "--empty"},
+	} {
+		if _, err := gs.runGit(worktreePath, args...); err != nil {
+			gs.RemoveWorktree(repoPath, worktreePath, true)
+			return fmt.Errorf("failed to add orphan worktree: %w", err)
+		}
+	}
+	return nil
+}
+
+// LockWorktree locks a worktree so that it is not pruned, moved or removed,
+// e.g. because it lives on a removable disk. The reason may be empty.
+func (gs *GitService) LockWorktree(repoPath, worktreePath, reason string) error {
+	args := []string{"worktree", "lock"}
+	if reason != "" {
+		args = append(args, "--reason", reason)
+	}
+	if _, err := gs.runGit(repoPath, append(args, worktreePath)...); err != nil {
+		return fmt.Errorf("failed to lock worktree: %w", err)
+	}
+	return nil
+}
+
+// UnlockWorktree unlocks a locked worktree
+func (gs *GitService) UnlockWorktree(repoPath, worktreePath string) error {
+	if _, err := gs.runGit(repoPath, "worktree", "unlock", worktreePath); err != nil {
+		return fmt.Errorf("failed to unlock worktree: %w", err)
+	}
+	return nil
+}
@@ -1,10 +1,12 @@
 package services
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -33,6 +35,28 @@ func TestGetGitVersionCommandExists(t *testing.T) {
 	}
 }
 
+func TestParsedGitVersionConcurrent(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed in test environment")
+	}
+	// Copies made by WithContext share the cache; run with -race to check it
+	gs := NewGitService("")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(gs *GitService) {
+			defer wg.Done()
+			if _, err := gs.ParsedGitVersion(); err != nil {
+				t.Errorf("ParsedGitVersion failed: %v", err)
+			}
+		}(gs.WithContext(context.Background()))
+	}
+	wg.Wait()
+	if gs.version.version == nil {
+		t.Errorf("version detected by a copy was not cached")
+	}
+}
+
 func TestSetUpstreamBranch(t *testing.T) {
 	if _, err := exec.LookPath("git"); err != nil {
 		t.Skip("git not installed in test environment")
@@ -127,3 +151,45 @@ func TestSetUpstreamBranch(t *testing.T) {
 	}
 }
 
+func TestParseWorktreeOutputLocked(t *testing.T) {
+	output := []byte("worktree /repo\nHEAD abc1234567\nbranch refs/heads/main\n\n" +
+		"worktree /wt/usb\nHEAD def7654321\nbranch refs/heads/usb\nlocked\n\n")
+
+	gs := NewGitService("")
+	worktrees, err := gs.parseWorktreeOutput(output)
+	if err != nil {
+		t.Fatalf("parseWorktreeOutput failed: %v", err)
+	}
+	if len(worktrees) != 2 {
+		t.Fatalf("expected 2 worktrees, got %d", len(worktrees))
+	}
+	if worktrees[0].IsLocked {
+		t.Errorf("main worktree should not be locked")
+	}
+	if !worktrees[1].IsLocked {
+		t.Errorf("worktree with bare 'locked' line should be locked")
+	}
+}
+
+func TestParseWorktreeOutputNulTerminated(t *testing.T) {
+	output := []byte("worktree /repo\x00HEAD abc1234567\x00branch refs/heads/main\x00\x00" +
+		"worktree /wt/odd\nname\x00HEAD def7654321\x00detached\x00locked moved to\nusb\x00\x00")
+
+	gs := NewGitService("")
+	worktrees, err := gs.parseWorktreeOutput(output)
+	if err != nil {
+		t.Fatalf("parseWorktreeOutput failed: %v", err)
+	}
+	if len(worktrees) != 2 {
+		t.Fatalf("expected 2 worktrees, got %d", len(worktrees))
+	}
+	if worktrees[1].Path != "/wt/odd\nname" {
+		t.Errorf("path = %q, want it to keep the newline", worktrees[1].Path)
+	}
+	if !worktrees[1].IsDetached || !worktrees[1].IsLocked {
+		t.Errorf("second worktree should be detached and locked: %+v", worktrees[1])
+	}
+	if worktrees[1].LockReason != "moved to\nusb" {
+		t.Errorf("lock reason = %q, want it to keep the newline", worktrees[1].LockReason)
+	}
+}
@@ -0,0 +1,62 @@
+// Support for bare repositories with sibling worktrees ("hub" layout)
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IsBareRepository reports whether the repository at repoPath is bare. It also
+// holds inside the linked worktrees of a bare repository.
+func (gs *GitService) IsBareRepository(repoPath string) bool {
+	value, err := gs.runGit(repoPath, "config", "--bool", "core.bare")
+	return err == nil && value == "true"
+}
+
+// CloneBare clones url into the bare repository gitDir. Unlike 'git clone --bare'
+// alone, it sets up remote-tracking branches so that worktrees can track and
+// fetch them like in a regular clone.
+func (gs *GitService) CloneBare(url, gitDir string) error {
+	if _, err := gs.runGit("", "clone", "--bare", "--quiet", url, gitDir); err != nil {
+		return fmt.Errorf("failed to clone %s: %w", url, err)
+	}
+	if _, err := gs.runGit(gitDir, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return fmt.Errorf("failed to set fetch refspec: %w", err)
+	}
+	if _, err := gs.runGit(gitDir, "fetch", "--quiet", "origin"); err != nil {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+	// An empty repository has no HEAD to point origin/HEAD at
+	gs.runGit(gitDir, "remote", "set-head", "origin", "--auto")
+	return nil
+}
+
+// InitBare creates an empty bare repository at gitDir
+func (gs *GitService) InitBare(gitDir string) error {
+	if _, err := gs.runGit("", "init", "--bare", "--quiet", gitDir); err != nil {
+		return fmt.Errorf("failed to create repository: %w", err)
+	}
+	return nil
+}
+
+// GetHeadBranch returns the branch HEAD points to in repoPath, which for a bare
+// repository is its default branch
+func (gs *GitService) GetHeadBranch(repoPath string) (string, error) {
+	branch, err := gs.runGit(repoPath, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD branch: %w", err)
+	}
+	return branch, nil
+}
+
+// GetLocalBranches returns the names of the repository's local branches
+func (gs *GitService) GetLocalBranches(repoPath string) ([]string, error) {
+	output, err := gs.runGit(repoPath, "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
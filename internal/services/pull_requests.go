@@ -0,0 +1,36 @@
+// Fetching of pull and merge request heads
+package services
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// pullRequestRefs are the refs hosting services publish pull request heads under:
+// GitHub uses refs/pull/<n>/head and GitLab refs/merge-requests/<n>/head
+var pullRequestRefs = []string{
+	"refs/pull/%d/head",
+	"refs/merge-requests/%d/head",
+}
+
+// PullRequestRef returns the local ref a fetched pull request head is stored in.
+// It lives outside refs/remotes so that 'git fetch --prune' does not delete it.
+func PullRequestRef(number int) string {
+	return "refs/wt/pr/" + strconv.Itoa(number)
+}
+
+// FetchPullRequest fetches the head of pull (or merge) request number from remote
+// into PullRequestRef(number) and returns the commit it points to
+func (gs *GitService) FetchPullRequest(repoPath, remote string, number int) (string, error) {
+	local := PullRequestRef(number)
+	var lastErr error
+	for _, pattern := range pullRequestRefs {
+		source := fmt.Sprintf(pattern, number)
+		_, err := gs.runGit(repoPath, "fetch", "--quiet", "--no-tags", remote, "+"+source+":"+local)
+		if err == nil {
+			return gs.ResolveRef(repoPath, local)
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("pull request #%d not found on remote %q: %w", number, remote, lastErr)
+}
@@ -0,0 +1,121 @@
+// User-level registry of repositories, stored in the global git config
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/models"
+)
+
+// The registry holds one wt.repo.<name>.path key per repository in the global
+// git config. The name is a subsection, so it may contain any character.
+const (
+	registryPrefix = "wt.repo."
+	registrySuffix = ".path"
+	groupPrefix    = "wt.group."
+)
+
+// GetRegisteredRepos returns the repositories in the registry, sorted by name
+func (gs *GitService) GetRegisteredRepos() ([]models.RegisteredRepo, error) {
+	cmd := gs.command("config", "--global", "--get-regexp", `^wt\.repo\..*\.path$`)
+	output, err := cmd.Output()
+	if err != nil {
+		// git config exits with status 1 when no key matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read repository registry: %w", err)
+	}
+	return parseRegistryOutput(output), nil
+}
+
+// parseRegistryOutput parses the output of 'git config --get-regexp' for the registry keys
+func parseRegistryOutput(output []byte) []models.RegisteredRepo {
+	var repos []models.RegisteredRepo
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, path, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || !strings.HasPrefix(key, registryPrefix) || !strings.HasSuffix(key, registrySuffix) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, registryPrefix), registrySuffix)
+		if name == "" {
+			continue
+		}
+		repos = append(repos, models.RegisteredRepo{Name: name, Path: strings.TrimSpace(path)})
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	return repos
+}
+
+// CheckRepoName fails if name cannot be used in the registry. Names may not
+// contain whitespace, which git config output cannot delimit, or commas, which
+// separate names in lists such as --repos.
+func CheckRepoName(name string) error {
+	if name == "" || strings.ContainsAny(name, ", \t\n") {
+		return fmt.Errorf("invalid repository name %q: it must not be empty or contain spaces or commas", name)
+	}
+	return nil
+}
+
+// RegisterRepo adds the repository at path to the registry as name
+func (gs *GitService) RegisterRepo(name, path string) error {
+	if err := CheckRepoName(name); err != nil {
+		return err
+	}
+	if _, err := gs.runGit("", "config", "--global", registryPrefix+name+registrySuffix, path); err != nil {
+		return fmt.Errorf("failed to register %s: %w", name, err)
+	}
+	return nil
+}
+
+// UnregisterRepo removes the repository name from the registry
+func (gs *GitService) UnregisterRepo(name string) error {
+	if _, err := gs.runGit("", "config", "--global", "--remove-section", registryPrefix+name); err != nil {
+		return fmt.Errorf("failed to unregister %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetRepoGroups returns the repository groups of the global git config. A group
+// lists registered repository names in wt.group.<name>, which may be repeated or
+// hold several comma-separated names. Group names are lower case, as git config
+// variable names are case-insensitive.
+func (gs *GitService) GetRepoGroups() (map[string][]string, error) {
+	cmd := gs.command("config", "--global", "--get-regexp", `^wt\.group\.`)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return map[string][]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read repository groups: %w", err)
+	}
+	return parseGroupOutput(output), nil
+}
+
+// parseGroupOutput parses the output of 'git config --get-regexp' for the group keys
+func parseGroupOutput(output []byte) map[string][]string {
+	groups := map[string][]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		name := strings.TrimPrefix(key, groupPrefix)
+		if name == key || name == "" || strings.Contains(name, ".") {
+			continue
+		}
+		for _, repo := range strings.Split(value, ",") {
+			if repo = strings.TrimSpace(repo); repo != "" {
+				groups[name] = append(groups[name], repo)
+			}
+		}
+	}
+	return groups
+}
@@ -0,0 +1,43 @@
+package services
+
+import "testing"
+
+func TestParseRegistryOutput(t *testing.T) {
+	output := []byte("wt.repo.web.path /src/web\nwt.repo.api.path /src/api\nwt.repo..path /nowhere\n")
+
+	repos := parseRegistryOutput(output)
+	if len(repos) != 2 {
+		t.Fatalf("repos = %+v, want 2", repos)
+	}
+	if repos[0].Name != "api" || repos[0].Path != "/src/api" || repos[1].Name != "web" || repos[1].Path != "/src/web" {
+		t.Errorf("repos = %+v, want api and web sorted by name", repos)
+	}
+}
+
+func TestCheckRepoName(t *testing.T) {
+	for _, name := range []string{"api", "my-app.v2", "team/web"} {
+		if err := CheckRepoName(name); err != nil {
+			t.Errorf("CheckRepoName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "my app", "api,web"} {
+		if err := CheckRepoName(name); err == nil {
+			t.Errorf("CheckRepoName(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestParseGroupOutput(t *testing.T) {
+	output := []byte("wt.group.fullstack api,web\nwt.group.fullstack docs\nwt.group.mobile ios , android\nwt.group.bad.key x\n")
+
+	groups := parseGroupOutput(output)
+	if got := groups["fullstack"]; len(got) != 3 || got[0] != "api" || got[1] != "web" || got[2] != "docs" {
+		t.Errorf("fullstack = %v, want [api web docs]", got)
+	}
+	if got := groups["mobile"]; len(got) != 2 || got[0] != "ios" || got[1] != "android" {
+		t.Errorf("mobile = %v, want [ios android]", got)
+	}
+	if len(groups) != 2 {
+		t.Errorf("groups = %v, want fullstack and mobile only", groups)
+	}
+}
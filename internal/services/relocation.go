@@ -0,0 +1,76 @@
+// Journal of worktree relocations, kept in the repository's git directory
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/smoerfugl/wt/internal/models"
+)
+
+// relocationFile is the name of the journal in the common git directory
+const relocationFile = "wt-relocate.json"
+
+// LoadRelocation returns the relocation in progress, or nil if there is none
+func (gs *GitService) LoadRelocation(repoPath string) (*models.Relocation, error) {
+	path, err := gs.relocationPath(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relocation journal: %w", err)
+	}
+	var relocation models.Relocation
+	if err := json.Unmarshal(data, &relocation); err != nil {
+		return nil, fmt.Errorf("invalid relocation journal %s: %w", path, err)
+	}
+	return &relocation, nil
+}
+
+// SaveRelocation writes the journal of a relocation that is about to start
+func (gs *GitService) SaveRelocation(repoPath string, relocation *models.Relocation) error {
+	path, err := gs.relocationPath(repoPath)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(relocation, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so an interruption never leaves half a journal
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write relocation journal: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write relocation journal: %w", err)
+	}
+	return nil
+}
+
+// ClearRelocation removes the journal of a finished relocation
+func (gs *GitService) ClearRelocation(repoPath string) error {
+	path, err := gs.relocationPath(repoPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove relocation journal: %w", err)
+	}
+	return nil
+}
+
+func (gs *GitService) relocationPath(repoPath string) (string, error) {
+	commonDir, err := gs.GetGitCommonDir(repoPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(commonDir, relocationFile), nil
+}
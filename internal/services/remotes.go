@@ -0,0 +1,66 @@
+// Resolution of the remotes used for new branches
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// defaultRemote is the remote used when nothing else is configured
+const defaultRemote = "origin"
+
+// GetConfigValue returns the value of a git config key, or "" if it is not set
+func (gs *GitService) GetConfigValue(repoPath, key string) string {
+	value, err := gs.runGit(repoPath, "config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// SetConfigValue sets a git config key in the repository's local config
+func (gs *GitService) SetConfigValue(repoPath, key, value string) error {
+	if _, err := gs.runGit(repoPath, "config", key, value); err != nil {
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetBaseRemote returns the remote that holds the canonical repository, which
+// new branches start from. It is read from wt.baseRemote, then git's
+// checkout.defaultRemote. Without either it is "origin", or the only remote if
+// the repository has exactly one.
+func (gs *GitService) GetBaseRemote(repoPath string) string {
+	for _, key := range []string{"wt.baseRemote", "checkout.defaultRemote"} {
+		if remote := gs.GetConfigValue(repoPath, key); remote != "" {
+			return remote
+		}
+	}
+
+	remotes, _ := gs.GetRemotes(repoPath)
+	if len(remotes) == 1 && !slices.Contains(remotes, defaultRemote) {
+		return remotes[0]
+	}
+	return defaultRemote
+}
+
+// GetPushRemote returns the remote new branches are pushed to and tracked
+// against, e.g. a personal fork. It is read from wt.pushRemote, then git's
+// remote.pushDefault, and falls back to the base remote.
+func (gs *GitService) GetPushRemote(repoPath string) string {
+	for _, key := range []string{"wt.pushRemote", "remote.pushDefault"} {
+		if remote := gs.GetConfigValue(repoPath, key); remote != "" {
+			return remote
+		}
+	}
+	return gs.GetBaseRemote(repoPath)
+}
+
+// SetBranchPushRemote makes 'git push' on branchName go to remote
+func (gs *GitService) SetBranchPushRemote(repoPath, branchName, remote string) error {
+	if _, err := gs.runGit(repoPath, "config", "branch."+branchName+".pushRemote", remote); err != nil {
+		return fmt.Errorf("failed to set push remote: %w", err)
+	}
+	return nil
+}
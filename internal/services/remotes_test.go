@@ -0,0 +1,59 @@
+package services
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestBaseAndPushRemoteResolution(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed in test environment")
+	}
+	repoDir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	gs := NewGitService("")
+
+	if got := gs.GetBaseRemote(repoDir); got != "origin" {
+		t.Errorf("base remote without remotes = %q, want origin", got)
+	}
+
+	git("remote", "add", "upstream", "https://example.com/upstream.git")
+	if got := gs.GetBaseRemote(repoDir); got != "upstream" {
+		t.Errorf("base remote with a single remote = %q, want upstream", got)
+	}
+
+	git("remote", "add", "origin", "https://example.com/fork.git")
+	if got := gs.GetBaseRemote(repoDir); got != "origin" {
+		t.Errorf("base remote with origin = %q, want origin", got)
+	}
+
+	git("config", "checkout.defaultRemote", "upstream")
+	if got := gs.GetBaseRemote(repoDir); got != "upstream" {
+		t.Errorf("base remote from checkout.defaultRemote = %q, want upstream", got)
+	}
+	if got := gs.GetPushRemote(repoDir); got != "upstream" {
+		t.Errorf("push remote falling back to base = %q, want upstream", got)
+	}
+
+	git("config", "remote.pushDefault", "origin")
+	if got := gs.GetPushRemote(repoDir); got != "origin" {
+		t.Errorf("push remote from remote.pushDefault = %q, want origin", got)
+	}
+
+	git("config", "wt.baseRemote", "origin")
+	git("config", "wt.pushRemote", "upstream")
+	if got := gs.GetBaseRemote(repoDir); got != "origin" {
+		t.Errorf("base remote from wt.baseRemote = %q, want origin", got)
+	}
+	if got := gs.GetPushRemote(repoDir); got != "upstream" {
+		t.Errorf("push remote from wt.pushRemote = %q, want upstream", got)
+	}
+}
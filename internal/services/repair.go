@@ -0,0 +1,158 @@
+// Detection of worktrees that were moved by hand or are no longer registered
+package services
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxWorktreeSearchDepth bounds how deep worktree directories are searched for below a root
+const maxWorktreeSearchDepth = 3
+
+// FindMovedWorktree looks for the directory a worktree registered at missingPath
+// was moved to. A moved worktree still has a .git file pointing at its
+// administrative directory in the repository, so the search roots are scanned
+// for such a file. It returns "" if the worktree cannot be found.
+func (gs *GitService) FindMovedWorktree(repoPath, missingPath string, searchRoots []string) (string, error) {
+	adminDir, err := gs.findAdminDir(repoPath, missingPath)
+	if err != nil || adminDir == "" {
+		return "", err
+	}
+
+	for _, root := range searchRoots {
+		if found := findGitdirLink(root, adminDir); found != "" && found != missingPath {
+			return found, nil
+		}
+	}
+	return "", nil
+}
+
+// findAdminDir returns the .git/worktrees/<id> directory registered for worktreePath
+func (gs *GitService) findAdminDir(repoPath, worktreePath string) (string, error) {
+	commonDir, err := gs.GetGitCommonDir(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(commonDir, "worktrees"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	for _, entry := range entries {
+		adminDir := filepath.Join(commonDir, "worktrees", entry.Name())
+		content, err := os.ReadFile(filepath.Join(adminDir, "gitdir"))
+		if err != nil {
+			continue
+		}
+		// The gitdir file holds the path of the worktree's .git file
+		if filepath.Dir(strings.TrimSpace(string(content))) == filepath.Clean(worktreePath) {
+			return adminDir, nil
+		}
+	}
+	return "", nil
+}
+
+// findGitdirLink walks root looking for a worktree whose .git file points at adminDir
+func findGitdirLink(root, adminDir string) string {
+	var found string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return filepath.SkipDir
+		}
+		if rel != "." && (strings.HasPrefix(d.Name(), ".") || strings.Count(rel, string(filepath.Separator)) >= maxWorktreeSearchDepth) {
+			return filepath.SkipDir
+		}
+
+		info, statErr := os.Lstat(filepath.Join(path, ".git"))
+		if statErr != nil {
+			return nil
+		}
+		// Directories with a .git entry are worktrees or repositories; never descend into them
+		if info.Mode().IsRegular() && readGitdirLink(filepath.Join(path, ".git")) == adminDir {
+			found = path
+			return filepath.SkipAll
+		}
+		if rel != "." {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return found
+}
+
+// readGitdirLink returns the target of a "gitdir: <path>" .git file
+func readGitdirLink(gitFile string) string {
+	content, err := os.ReadFile(gitFile)
+	if err != nil {
+		return ""
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	return filepath.Clean(strings.TrimSpace(target))
+}
+
+// FindUnregisteredWorktrees returns directories under the worktrees directory that
+// look like worktrees (they contain a .git file) but are not registered with git
+func (gs *GitService) FindUnregisteredWorktrees(repoPath string) ([]string, error) {
+	worktrees, err := gs.GetWorktrees(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[string]bool)
+	for _, wt := range worktrees {
+		registered[resolvePath(wt.Path)] = true
+	}
+
+	var orphans []string
+	root := gs.GetWorktreesDir(repoPath)
+	for _, dir := range findWorktreeDirs(root) {
+		if !registered[resolvePath(dir)] {
+			orphans = append(orphans, dir)
+		}
+	}
+	return orphans, nil
+}
+
+// findWorktreeDirs returns the directories below root that contain a .git file
+func findWorktreeDirs(root string) []string {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil || rel == "." {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || strings.Count(rel, string(filepath.Separator)) >= maxWorktreeSearchDepth {
+			return filepath.SkipDir
+		}
+		if info, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+			if info.Mode().IsRegular() {
+				dirs = append(dirs, path)
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs
+}
+
+// resolvePath returns path with symlinks resolved, or path itself if that fails
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
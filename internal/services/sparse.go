@@ -0,0 +1,66 @@
+// Sparse-checkout support for worktrees of large repositories
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/models"
+)
+
+// SetSparseDirs limits the worktree at worktreePath to dirs using cone-mode sparse-checkout.
+// It only changes the sparse patterns; CheckoutHead populates a worktree created with
+// --no-checkout.
+func (gs *GitService) SetSparseDirs(worktreePath string, dirs []string) error {
+	if err := gs.RequireCapability(models.CapSparseCheckout); err != nil {
+		return err
+	}
+	if _, err := gs.runGit(worktreePath, "sparse-checkout", "init", "--cone"); err != nil {
+		return fmt.Errorf("failed to enable sparse-checkout: %w", err)
+	}
+	if _, err := gs.runGit(worktreePath, append([]string{"sparse-checkout", "set"}, dirs...)...); err != nil {
+		return fmt.Errorf("failed to set sparse-checkout directories: %w", err)
+	}
+	return nil
+}
+
+// AddSparseDirs adds dirs to the sparse-checkout of the worktree at worktreePath
+func (gs *GitService) AddSparseDirs(worktreePath string, dirs []string) error {
+	if !gs.IsSparse(worktreePath) {
+		return fmt.Errorf("%s is not a sparse worktree", worktreePath)
+	}
+	if _, err := gs.runGit(worktreePath, append([]string{"sparse-checkout", "add"}, dirs...)...); err != nil {
+		return fmt.Errorf("failed to add sparse-checkout directories: %w", err)
+	}
+	return nil
+}
+
+// GetSparseDirs returns the directories checked out in the sparse worktree at worktreePath
+func (gs *GitService) GetSparseDirs(worktreePath string) ([]string, error) {
+	if !gs.IsSparse(worktreePath) {
+		return nil, fmt.Errorf("%s is not a sparse worktree", worktreePath)
+	}
+	output, err := gs.runGit(worktreePath, "sparse-checkout", "list")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sparse-checkout directories: %w", err)
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// IsSparse reports whether the worktree at worktreePath uses sparse-checkout
+func (gs *GitService) IsSparse(worktreePath string) bool {
+	value, err := gs.runGit(worktreePath, "config", "--bool", "core.sparseCheckout")
+	return err == nil && value == "true"
+}
+
+// CheckoutHead populates a worktree that was created with --no-checkout,
+// honouring its sparse-checkout patterns
+func (gs *GitService) CheckoutHead(worktreePath string) error {
+	if _, err := gs.runGit(worktreePath, "checkout"); err != nil {
+		return fmt.Errorf("failed to check out worktree: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Status collects the state of a worktree for 'wt status'
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smoerfugl/wt/internal/models"
+)
+
+// GetWorktreeStatus returns the changes, upstream divergence, last commit and
+// in-progress operation of the worktree at worktreePath
+func (gs *GitService) GetWorktreeStatus(worktreePath string) (*models.WorktreeStatus, error) {
+	status := &models.WorktreeStatus{}
+
+	output, err := gs.runGit(worktreePath, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of %s: %w", worktreePath, err)
+	}
+	parseStatusOutput(output, status)
+
+	// An unborn branch has no commits
+	if log, err := gs.runGit(worktreePath, "log", "-1", "--format=%ct %s"); err == nil && log != "" {
+		timestamp, subject, _ := strings.Cut(log, " ")
+		if seconds, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+			committed := time.Unix(seconds, 0)
+			status.LastCommitTime = &committed
+		}
+		status.LastCommitSubject = subject
+	}
+
+	gitDir, err := gs.runGit(worktreePath, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find git directory of %s: %w", worktreePath, err)
+	}
+	status.Operation = detectOperation(gitDir)
+
+	return status, nil
+}
+
+// parseStatusOutput fills in status from the output of 'git status --porcelain=v2 --branch'
+func parseStatusOutput(output string, status *models.WorktreeStatus) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# branch.upstream "):
+			status.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			// # branch.ab +<ahead> -<behind>
+			fields := strings.Fields(strings.TrimPrefix(line, "# branch.ab "))
+			if len(fields) == 2 {
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+			}
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "):
+			// Ordinary and renamed entries: "1 XY ...", X is the index, Y the worktree
+			if len(line) < 4 {
+				continue
+			}
+			if line[2] != '.' {
+				status.Staged++
+			}
+			if line[3] != '.' {
+				status.Modified++
+			}
+		case strings.HasPrefix(line, "u "):
+			status.Conflicts++
+		case strings.HasPrefix(line, "? "):
+			status.Untracked++
+		}
+	}
+}
+
+// detectOperation returns the operation in progress in the worktree whose
+// private git directory is gitDir, or "" if there is none
+func detectOperation(gitDir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("rebase-merge"):
+		return models.OperationRebase
+	case exists("rebase-apply"):
+		// 'git am' uses the same directory as the apply backend of rebase
+		if exists(filepath.Join("rebase-apply", "applying")) {
+			return models.OperationAm
+		}
+		return models.OperationRebase
+	case exists("MERGE_HEAD"):
+		return models.OperationMerge
+	case exists("CHERRY_PICK_HEAD"):
+		return models.OperationCherryPick
+	case exists("REVERT_HEAD"):
+		return models.OperationRevert
+	case exists("BISECT_LOG"):
+		return models.OperationBisect
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smoerfugl/wt/internal/models"
+)
+
+func TestParseStatusOutput(t *testing.T) {
+	output := "# branch.oid 1234567\n# branch.head feature\n# branch.upstream origin/feature\n# branch.ab +2 -1\n" +
+		"1 M. N... 100644 100644 100644 abc abc staged.go\n" +
+		"1 .M N... 100644 100644 100644 abc abc modified.go\n" +
+		"1 MM N... 100644 100644 100644 abc abc both.go\n" +
+		"2 R. N... 100644 100644 100644 abc abc R100 new.go\told.go\n" +
+		"u UU N... 100644 100644 100644 100644 abc abc abc conflict.go\n" +
+		"? untracked.txt\n"
+
+	var status models.WorktreeStatus
+	parseStatusOutput(output, &status)
+
+	if status.Upstream != "origin/feature" || status.Ahead != 2 || status.Behind != 1 {
+		t.Errorf("upstream = %q +%d -%d, want origin/feature +2 -1", status.Upstream, status.Ahead, status.Behind)
+	}
+	if status.Staged != 3 || status.Modified != 2 || status.Conflicts != 1 || status.Untracked != 1 {
+		t.Errorf("counts = staged %d modified %d conflicts %d untracked %d, want 3 2 1 1",
+			status.Staged, status.Modified, status.Conflicts, status.Untracked)
+	}
+}
+
+func TestDetectOperation(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"rebase-merge/"}, models.OperationRebase},
+		{[]string{"rebase-apply/"}, models.OperationRebase},
+		{[]string{"rebase-apply/", "rebase-apply/applying"}, models.OperationAm},
+		{[]string{"MERGE_HEAD"}, models.OperationMerge},
+		{[]string{"CHERRY_PICK_HEAD"}, models.OperationCherryPick},
+		{[]string{"REVERT_HEAD"}, models.OperationRevert},
+		{[]string{"BISECT_LOG"}, models.OperationBisect},
+	}
+
+	for _, tt := range tests {
+		gitDir := t.TempDir()
+		for _, name := range tt.files {
+			path := filepath.Join(gitDir, name)
+			var err error
+			if name[len(name)-1] == '/' {
+				err = os.MkdirAll(path, 0755)
+			} else {
+				err = os.WriteFile(path, nil, 0644)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := detectOperation(gitDir); got != tt.want {
+			t.Errorf("detectOperation(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Submodule support for new worktrees
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// HasSubmodules reports whether the worktree at worktreePath declares submodules
+func (gs *GitService) HasSubmodules(worktreePath string) bool {
+	_, err := os.Stat(filepath.Join(worktreePath, ".gitmodules"))
+	return err == nil
+}
+
+// GetSubmodules returns the path of each submodule declared in .gitmodules, keyed by name
+func (gs *GitService) GetSubmodules(worktreePath string) (map[string]string, error) {
+	cmd := gs.command("config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`)
+	cmd.Dir = worktreePath
+	output, err := cmd.Output()
+	if err != nil {
+		// git config exits with status 1 when no key matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read .gitmodules: %w", err)
+	}
+
+	submodules := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		key, path, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "submodule."), ".path")
+		submodules[name] = path
+	}
+	return submodules, nil
+}
+
+// FindSubmoduleStore returns the git directory of submodule name as already cloned
+// for the repository at repoPath, or "" if it has not been cloned
+func (gs *GitService) FindSubmoduleStore(repoPath, name, path string) string {
+	var candidates []string
+	if commonDir, err := gs.GetGitCommonDir(repoPath); err == nil {
+		candidates = append(candidates, filepath.Join(commonDir, "modules", name))
+	}
+	if mainWorktree, err := gs.GetMainWorktree(repoPath); err == nil {
+		// Submodules cloned before git absorbed them into .git/modules
+		candidates = append(candidates, filepath.Join(mainWorktree, path, ".git"))
+	}
+
+	for _, dir := range candidates {
+		if info, err := os.Stat(filepath.Join(dir, "objects")); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
+
+// UpdateSubmodules initializes and checks out the submodules of the worktree at
+// worktreePath, recursively. Submodules with an entry in references copy objects
+// from that git directory instead of downloading them again. They do not keep
+// borrowing from it, so removing the other worktree cannot corrupt them.
+func (gs *GitService) UpdateSubmodules(worktreePath string, references map[string]string) error {
+	for path, reference := range references {
+		if _, err := gs.runGit(worktreePath, "submodule", "update", "--init", "--reference", reference, "--dissociate", "--", path); err != nil {
+			return fmt.Errorf("failed to update submodule %s: %w", path, err)
+		}
+	}
+	// Submodules without a local copy, and nested submodules
+	if _, err := gs.runGit(worktreePath, "submodule", "update", "--init", "--recursive"); err != nil {
+		return fmt.Errorf("failed to update submodules: %w", err)
+	}
+	return nil
+}
+
+// CountUninitializedSubmodules returns how many submodules of the worktree at
+// worktreePath have not been initialized
+func (gs *GitService) CountUninitializedSubmodules(worktreePath string) (int, error) {
+	if !gs.HasSubmodules(worktreePath) {
+		return 0, nil
+	}
+	output, err := gs.runGit(worktreePath, "submodule", "status")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get submodule status: %w", err)
+	}
+	count := 0
+	for _, line := range strings.Split(output, "\n") {
+		// Uninitialized submodules are prefixed with '-'
+		if strings.HasPrefix(line, "-") {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// HasInitializedSubmodules reports whether any submodule of the worktree at
+// worktreePath is checked out, which makes git refuse to move or remove it
+func (gs *GitService) HasInitializedSubmodules(worktreePath string) bool {
+	if !gs.HasSubmodules(worktreePath) {
+		return false
+	}
+	count, err := gs.CountUninitializedSubmodules(worktreePath)
+	if err != nil {
+		return false
+	}
+	submodules, err := gs.GetSubmodules(worktreePath)
+	return err == nil && len(submodules) > count
+}
+
+// isWorktreeCleanWithSubmodules reports whether the worktree and its submodules
+// have no staged, unstaged or untracked changes, whatever submodule.<name>.ignore says
+func (gs *GitService) isWorktreeCleanWithSubmodules(worktreePath string) (bool, error) {
+	output, err := gs.runGit(worktreePath, "status", "--porcelain", "--ignore-submodules=none")
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	return strings.TrimSpace(output) == "", nil
+}
+
+// ReconnectSubmodules rewrites the links between the initialized submodules of the
+// worktree at worktreePath and their git directories. git stores them as relative
+// paths, which break when a worktree is copied to a new location.
+func (gs *GitService) ReconnectSubmodules(worktreePath string) error {
+	if !gs.HasSubmodules(worktreePath) {
+		return nil
+	}
+	submodules, err := gs.GetSubmodules(worktreePath)
+	if err != nil {
+		return err
+	}
+	gitDir, err := gs.runGit(worktreePath, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return fmt.Errorf("failed to get git directory: %w", err)
+	}
+
+	for name, path := range submodules {
+		moduleDir := filepath.Join(gitDir, "modules", name)
+		workTree := filepath.Join(worktreePath, path)
+		if _, err := os.Stat(filepath.Join(moduleDir, "config")); err != nil {
+			// Not initialized in this worktree
+			continue
+		}
+		toGitDir, err := filepath.Rel(workTree, moduleDir)
+		if err != nil {
+			return err
+		}
+		toWorkTree, err := filepath.Rel(moduleDir, workTree)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(workTree, ".git"), []byte("gitdir: "+filepath.ToSlash(toGitDir)+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to reconnect submodule %s: %w", path, err)
+		}
+		if _, err := gs.runGit(worktreePath, "config", "--file", filepath.Join(moduleDir, "config"), "core.worktree", filepath.ToSlash(toWorkTree)); err != nil {
+			return fmt.Errorf("failed to reconnect submodule %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Files provides helpers for copying local files between worktrees
+package utils
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CopyResult reports what CopyNoClobber did
+type CopyResult struct {
+	Copied      int      // Files and symlinks created in dst
+	Skipped     []string // Existing files in dst that were left alone
+	Unsupported []string // Sockets, FIFOs and devices in src, which are not copied
+}
+
+// CopyNoClobber copies the file or directory src to dst.
+// Existing files in dst are never overwritten; they are reported as skipped.
+// Special files such as sockets cannot be copied and are reported as unsupported.
+func CopyNoClobber(src, dst string) (CopyResult, error) {
+	var result CopyResult
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			if _, err := os.Lstat(target); err == nil {
+				result.Skipped = append(result.Skipped, target)
+				return nil
+			}
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+			result.Copied++
+			return nil
+		case info.Mode().IsRegular():
+			if _, err := os.Lstat(target); err == nil {
+				result.Skipped = append(result.Skipped, target)
+				return nil
+			}
+			if err := copyFile(path, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			result.Copied++
+			return nil
+		default:
+			result.Unsupported = append(result.Unsupported, path)
+			return nil
+		}
+	})
+	return result, err
+}
+
+// SymlinkNoClobber creates a symlink at dst pointing to the absolute path of src.
+// It reports false if dst already exists, which is left untouched.
+func SymlinkNoClobber(src, dst string) (bool, error) {
+	if _, err := os.Lstat(dst); err == nil {
+		return false, nil
+	}
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	if err := os.Symlink(absSrc, dst); err != nil {
+		return false, fmt.Errorf("failed to link %s: %w", dst, err)
+	}
+	return true, nil
+}
+
+// copyFile copies a regular file, failing if dst already exists
+func copyFile(src, dst string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
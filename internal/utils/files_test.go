@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyNoClobber(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "certs"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	os.WriteFile(filepath.Join(src, "certs", "a.pem"), []byte("new-a"), 0600)
+	os.WriteFile(filepath.Join(src, "certs", "b.pem"), []byte("new-b"), 0600)
+	os.MkdirAll(filepath.Join(dst, "certs"), 0755)
+	os.WriteFile(filepath.Join(dst, "certs", "a.pem"), []byte("old-a"), 0600)
+
+	result, err := CopyNoClobber(filepath.Join(src, "certs"), filepath.Join(dst, "certs"))
+	if err != nil {
+		t.Fatalf("CopyNoClobber failed: %v", err)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != filepath.Join(dst, "certs", "a.pem") {
+		t.Errorf("skipped = %v, want [certs/a.pem]", result.Skipped)
+	}
+	if result.Copied != 1 {
+		t.Errorf("copied = %d, want 1", result.Copied)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "certs", "a.pem")); string(got) != "old-a" {
+		t.Errorf("existing file was overwritten: %q", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "certs", "b.pem")); string(got) != "new-b" {
+		t.Errorf("new file not copied: %q", got)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "certs", "b.pem")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("copied file lost its permissions: %v", info.Mode())
+	}
+}
+
+func TestCopyNoClobberReportsSpecialFiles(t *testing.T) {
+	src := t.TempDir()
+	listener, err := net.Listen("unix", filepath.Join(src, "s"))
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+	defer listener.Close()
+
+	result, err := CopyNoClobber(src, filepath.Join(t.TempDir(), "copy"))
+	if err != nil {
+		t.Fatalf("CopyNoClobber failed: %v", err)
+	}
+	if len(result.Unsupported) != 1 || result.Unsupported[0] != filepath.Join(src, "s") {
+		t.Errorf("unsupported = %v, want the socket", result.Unsupported)
+	}
+}
+
+func TestSymlinkNoClobber(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "cache")
+	dst := filepath.Join(t.TempDir(), "node_modules", "cache")
+	os.Mkdir(src, 0755)
+
+	linked, err := SymlinkNoClobber(src, dst)
+	if err != nil || !linked {
+		t.Fatalf("SymlinkNoClobber = %v, %v; want true, nil", linked, err)
+	}
+	if target, err := os.Readlink(dst); err != nil || target != src {
+		t.Errorf("link target = %q, %v; want %q", target, err, src)
+	}
+
+	linked, err = SymlinkNoClobber(src, dst)
+	if err != nil || linked {
+		t.Errorf("second SymlinkNoClobber = %v, %v; want false, nil", linked, err)
+	}
+}
@@ -2,6 +2,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -16,6 +17,12 @@ func FormatBasic(worktrees []models.Worktree) string {
 		if wt.IsCurrent {
 			line += " (current)"
 		}
+		if mode := wt.GetMode(); mode != models.ModeBranch {
+			line += " (" + mode + ")"
+		}
+		if wt.IsLocked {
+			line += " (locked)"
+		}
 		result.WriteString(line + "\n")
 	}
 	return result.String()
@@ -31,6 +38,7 @@ func FormatVerbose(worktrees []models.Worktree) string {
 	nameWidth := len("NAME")
 	pathWidth := len("PATH")
 	branchWidth := len("BRANCH")
+	modeWidth := len("MODE")
 	commitWidth := len("COMMIT")
 	statusWidth := len("STATUS")
 	currentWidth := len("CURRENT")
@@ -45,19 +53,23 @@ func FormatVerbose(worktrees []models.Worktree) string {
 		if len(wt.Branch) > branchWidth {
 			branchWidth = len(wt.Branch)
 		}
+		if len(wt.GetMode()) > modeWidth {
+			modeWidth = len(wt.GetMode())
+		}
 		if len(wt.CommitHash) > commitWidth {
 			commitWidth = len(wt.CommitHash)
 		}
-		if len(wt.GetStatus()) > statusWidth {
-			statusWidth = len(wt.GetStatus())
+		if len(verboseStatus(wt)) > statusWidth {
+			statusWidth = len(verboseStatus(wt))
 		}
 	}
 
 	// Create header
-	header := fmt.Sprintf("%-*s  %-*s  %-*s  %-*s  %-*s  %-*s\n",
+	header := fmt.Sprintf("%-*s  %-*s  %-*s  %-*s  %-*s  %-*s  %-*s\n",
 		nameWidth, "NAME",
 		pathWidth, "PATH",
 		branchWidth, "BRANCH",
+		modeWidth, "MODE",
 		commitWidth, "COMMIT",
 		statusWidth, "STATUS",
 		currentWidth, "CURRENT")
@@ -70,12 +82,13 @@ func FormatVerbose(worktrees []models.Worktree) string {
 		if wt.IsCurrent {
 			currentMark = "✓"
 		}
-		row := fmt.Sprintf("%-*s  %-*s  %-*s  %-*s  %-*s  %-*s\n",
+		row := fmt.Sprintf("%-*s  %-*s  %-*s  %-*s  %-*s  %-*s  %-*s\n",
 			nameWidth, wt.Name,
 			pathWidth, wt.Path,
 			branchWidth, wt.Branch,
+			modeWidth, wt.GetMode(),
 			commitWidth, wt.CommitHash,
-			statusWidth, wt.GetStatus(),
+			statusWidth, verboseStatus(wt),
 			currentWidth, currentMark)
 		result += row
 	}
@@ -83,6 +96,19 @@ func FormatVerbose(worktrees []models.Worktree) string {
 	return result
 }
 
+// verboseStatus returns the worktree status with the lock reason, flagging
+// uninitialized submodules
+func verboseStatus(wt models.Worktree) string {
+	status := wt.GetStatus()
+	if wt.IsLocked {
+		status = wt.DescribeLock()
+	}
+	if wt.UninitializedSubmodules > 0 {
+		return fmt.Sprintf("%s, %d submodule(s) uninitialized", status, wt.UninitializedSubmodules)
+	}
+	return status
+}
+
 // FormatJSON outputs worktrees in JSON format
 func FormatJSON(worktrees []models.Worktree, repoPath string) string {
 	var result strings.Builder
@@ -95,10 +121,12 @@ func FormatJSON(worktrees []models.Worktree, repoPath string) string {
 		result.WriteString(fmt.Sprintf("      \"name\": \"%s\",\n", wt.Name))
 		result.WriteString(fmt.Sprintf("      \"path\": \"%s\",\n", wt.Path))
 		result.WriteString(fmt.Sprintf("      \"branch\": \"%s\",\n", wt.Branch))
+		result.WriteString(fmt.Sprintf("      \"mode\": \"%s\",\n", wt.GetMode()))
 		result.WriteString(fmt.Sprintf("      \"commit\": \"%s\",\n", wt.CommitHash))
 		result.WriteString(fmt.Sprintf("      \"status\": \"%s\",\n", wt.GetStatus()))
 		result.WriteString(fmt.Sprintf("      \"current\": %t,\n", wt.IsCurrent))
-		result.WriteString(fmt.Sprintf("      \"locked\": %t\n", wt.IsLocked))
+		result.WriteString(fmt.Sprintf("      \"locked\": %t,\n", wt.IsLocked))
+		result.WriteString(fmt.Sprintf("      \"lock_reason\": %s\n", jsonString(wt.LockReason)))
 		if i < len(worktrees)-1 {
 			result.WriteString("    },\n")
 		} else {
@@ -110,3 +138,12 @@ func FormatJSON(worktrees []models.Worktree, repoPath string) string {
 	result.WriteString("}\n")
 	return result.String()
 }
+
+// jsonString encodes s as a JSON string; lock reasons are free text and may need escaping
+func jsonString(s string) string {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return `""`
+	}
+	return string(encoded)
+}
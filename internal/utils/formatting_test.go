@@ -30,3 +30,25 @@ func TestFormatJSON(t *testing.T) {
 		t.Fatalf("json output unexpected: %s", js)
 	}
 }
+
+func TestFormatModes(t *testing.T) {
+	wts := []models.Worktree{
+		{Name: "scratch", Path: "/scratch", CommitHash: "a1b2c3", IsDetached: true, IsClean: true},
+		{Name: "docs", Path: "/docs", Branch: "docs", IsOrphan: true, IsLocked: true, LockReason: `on "USB" disk`, IsClean: true},
+	}
+
+	basic := FormatBasic(wts)
+	if !strings.Contains(basic, "(detached)") || !strings.Contains(basic, "(orphan)") || !strings.Contains(basic, "(locked)") {
+		t.Fatalf("basic output missing mode annotations: %s", basic)
+	}
+
+	verbose := FormatVerbose(wts)
+	if !strings.Contains(verbose, "MODE") || !strings.Contains(verbose, models.ModeOrphan) || !strings.Contains(verbose, `locked: on "USB" disk`) {
+		t.Fatalf("verbose output missing mode column: %s", verbose)
+	}
+
+	js := FormatJSON(wts, "/repo")
+	if !strings.Contains(js, "\"mode\": \"detached\"") || !strings.Contains(js, `"lock_reason": "on \"USB\" disk"`) {
+		t.Fatalf("json output missing mode: %s", js)
+	}
+}
@@ -0,0 +1,33 @@
+// Slug turns branch names into worktree directory names
+package utils
+
+import "strings"
+
+// Slug returns a directory name for a branch name. Path separators and other
+// characters that are awkward in paths become '-', so feature/login becomes
+// feature-login. Leading dots and dashes are dropped so the directory is not hidden.
+// The result is the same for the same input.
+func Slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_':
+			b.WriteRune(r)
+			dash = false
+		default:
+			// Collapse runs of separators into a single '-'
+			if !dash {
+				b.WriteByte('-')
+				dash = true
+			}
+		}
+	}
+
+	slug := strings.TrimLeft(b.String(), ".-")
+	slug = strings.TrimRight(slug, ".-")
+	if slug == "" {
+		return "worktree"
+	}
+	return slug
+}
@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"main", "main"},
+		{"feature/login", "feature-login"},
+		{"bugfix/login", "bugfix-login"},
+		{"user/jane/JIRA-123_fix", "user-jane-JIRA-123_fix"},
+		{"release/v1.2", "release-v1.2"},
+		{"feature//double", "feature-double"},
+		{"feature/ünïcode", "feature-n-code"},
+		{".hidden/", "hidden"},
+		{"pr/123", "pr-123"},
+		{"///", "worktree"},
+	}
+
+	for _, tt := range tests {
+		if got := Slug(tt.name); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
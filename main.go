@@ -3,24 +3,38 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/smoerfugl/wt/internal/commands"
 	"github.com/smoerfugl/wt/internal/utils"
+	"github.com/smoerfugl/wt/pkg/wt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-func runNewListCommand(verbose, jsonOutput bool, filter, branch string) error {
-	// Get the repository path
-	repoPath, err := gitTop()
-	if err != nil {
-		return err
+// runNewListCommand lists the worktrees of the repository the client resolves,
+// with the same code as Client.List
+func runNewListCommand(verbose, jsonOutput, allRepos bool, filter, branch string) error {
+	if allRepos {
+		// Registered repositories can be listed from anywhere
+		return commands.RunListCommand("", "git", verbose, jsonOutput, true, filter, branch)
+	}
+	return commands.RunListCommand(newClient().RepoPath(), "git", verbose, jsonOutput, false, filter, branch)
+}
+
+// runNewStatusCommand shows the status of the repository the client resolves,
+// with the same code as Client.Status
+func runNewStatusCommand(jsonOutput, dirty, unpushed, allRepos bool) error {
+	if allRepos {
+		// Registered repositories can be shown from anywhere
+		return commands.RunStatusCommand("", "git", jsonOutput, dirty, unpushed, true)
 	}
-	return commands.RunListCommand(repoPath, "git", verbose, jsonOutput, filter, branch)
+	return commands.RunStatusCommand(newClient().RepoPath(), "git", jsonOutput, dirty, unpushed, false)
 }
 
 func main() {
@@ -37,40 +51,64 @@ func main() {
 		jsonOutput := listCmd.Bool("j", false, "output in JSON format")
 		filter := listCmd.String("f", "", "filter worktrees by name pattern")
 		branch := listCmd.String("b", "", "filter worktrees by branch name")
+		allRepos := listCmd.Bool("all-repos", false, "list the worktrees of every registered repository")
 		if err := listCmd.Parse(os.Args[2:]); err != nil {
 			fatal(err)
 		}
-		if err := ensureRepo(); err != nil {
-			fatal(err)
+		if !*allRepos {
+			if err := ensureRepo(); err != nil {
+				fatal(err)
+			}
 		}
-		if err := runNewListCommand(*verbose, *jsonOutput, *filter, *branch); err != nil {
+		if err := runNewListCommand(*verbose, *jsonOutput, *allRepos, *filter, *branch); err != nil {
 			fatal(err)
 		}
 	case "add":
 		addCmd := flag.NewFlagSet("add", flag.ExitOnError)
 		createBranch := addCmd.Bool("b", false, "create a new branch with -b")
-		addCmd.String("exec", "", "command to execute in the worktree after creation")
-		addCmd.String("e", "", "command to execute in the worktree after creation (shorthand)")
-		addCmd.Parse(os.Args[2:])
-		args := addCmd.Args()
-
-		// Parse multiple --exec/-e flags manually
-		var execCommands []*utils.Command
-		for i := 0; i < len(os.Args[2:]); i++ {
-			if os.Args[2+i] == "--exec" || os.Args[2+i] == "-e" {
-				if i+1 < len(os.Args[2:]) {
-					cmdStr := os.Args[2+i+1]
-					cmd := utils.NewCommand("sh", []string{"-c", cmdStr})
-					if err := cmd.Validate(); err != nil {
-						fatal(fmt.Errorf("invalid --exec command: %w", err))
-					}
-					execCommands = append(execCommands, cmd)
-					i++ // Skip the command argument
-				}
+		keepOnFailure := addCmd.Bool("keep-on-failure", false, "keep the partially created worktree and branch if a step fails")
+		remote := addCmd.String("remote", "", "remote to track when the branch only exists on remotes")
+		prNumber := addCmd.Int("pr", 0, "check out pull/merge request <number> as branch pr/<number>")
+		refresh := addCmd.Bool("refresh", false, "with --pr, update an existing pull request worktree")
+		orphan := addCmd.Bool("orphan", false, "create <branch> as a new branch without history")
+		detach := addCmd.Bool("detach", false, "check out <commit> with a detached HEAD")
+		lock := addCmd.Bool("lock", false, "lock the new worktree")
+		lockReason := addCmd.String("reason", "", "with --lock, why the worktree is locked")
+		carry := addCmd.Bool("carry", false, "move uncommitted changes of the current worktree into the new worktree")
+		untracked := addCmd.Bool("u", false, "with --carry, also move untracked files")
+		addCmd.BoolVar(untracked, "include-untracked", false, "with --carry, also move untracked files")
+		dirName := addCmd.String("name", "", "directory name for the worktree instead of one derived from the branch")
+		noSubmodules := addCmd.Bool("no-submodules", false, "do not initialize submodules in the new worktree")
+		repos := addCmd.String("repos", "", "create the worktree in these registered repositories or groups (comma-separated)")
+		var sparseDirs, sparseProfiles stringList
+		addCmd.Var(&sparseDirs, "sparse", "only check out this directory (repeatable)")
+		addCmd.Var(&sparseProfiles, "sparse-profile", "only check out the directories of wt.sparse.<name> (repeatable)")
+		var execStrings stringList
+		addCmd.Var(&execStrings, "exec", "command to execute in the worktree after creation (repeatable)")
+		addCmd.Var(&execStrings, "e", "command to execute in the worktree after creation (shorthand)")
+		args := parseInterspersed(addCmd, os.Args[2:])
+
+		if *untracked && !*carry {
+			fatal(errors.New("--include-untracked requires --carry"))
+		}
+
+		if *refresh && *prNumber == 0 {
+			fatal(errors.New("--refresh requires --pr"))
+		}
+
+		if *prNumber != 0 {
+			// wt add --pr <number>
+			if *prNumber < 0 || *createBranch || len(args) > 0 {
+				fmt.Fprintln(os.Stderr, "usage: wt add --pr <number> [--refresh]")
+				os.Exit(2)
 			}
-		}
-
-		if *createBranch {
+		} else if *orphan || *detach {
+			// wt add --orphan <branch> | wt add --detach <commit>
+			if len(args) != 1 {
+				fmt.Fprintln(os.Stderr, "usage: wt add --orphan <branch> | wt add --detach <commit>")
+				os.Exit(2)
+			}
+		} else if *createBranch {
 			// wt add -b <new-branch> [<start-point>]
 			if len(args) < 1 {
 				fmt.Fprintln(os.Stderr, "usage: wt add -b <new-branch> [<start-point>]")
@@ -84,51 +122,377 @@ func main() {
 			}
 		}
 
-		if err := ensureRepo(); err != nil {
-			fatal(err)
-		}
-
-		// Get repository path
-		repoPath, err := gitTop()
-		if err != nil {
-			fatal(err)
-		}
-
 		// Determine branch name and start point
-		branchName := args[0]
+		branchName := ""
+		if len(args) > 0 {
+			branchName = args[0]
+		}
 		startPoint := ""
 		if *createBranch && len(args) >= 2 {
 			startPoint = args[1]
 		}
 
-		// Execute the add command
-		if err := commands.RunAddCommand(repoPath, "git", *createBranch, false, branchName, startPoint, execCommands); err != nil {
+		// With --repos the worktrees are created in registered repositories
+		if *repos != "" {
+			var execCommands []*utils.Command
+			for _, cmdStr := range execStrings {
+				cmd := utils.NewCommand("sh", []string{"-c", cmdStr})
+				if err := cmd.Validate(); err != nil {
+					fatal(fmt.Errorf("invalid --exec command: %w", err))
+				}
+				execCommands = append(execCommands, cmd)
+			}
+			opts := commands.AddOptions{
+				CreateBranch:  *createBranch,
+				BranchName:    branchName,
+				StartPoint:    startPoint,
+				ExecCommands:  execCommands,
+				KeepOnFailure: *keepOnFailure,
+				Carry:         *carry,
+				Untracked:     *untracked,
+				Remote:        *remote,
+				PRNumber:      *prNumber,
+				Refresh:       *refresh,
+				Orphan:        *orphan,
+				Detach:        *detach,
+				Lock:          *lock,
+				LockReason:    *lockReason,
+				Sparse:        sparseDirs,
+				SparseProfile: sparseProfiles,
+				NoSubmodules:  *noSubmodules,
+				Name:          *dirName,
+				Repos:         splitRepos(*repos),
+			}
+			if err := commands.RunAddCommand("", "git", opts); err != nil {
+				fatal(err)
+			}
+			return
+		}
+
+		client := newClient()
+		_, err := client.Add(context.Background(), wt.AddOptions{
+			Branch:           branchName,
+			Create:           *createBranch,
+			StartPoint:       startPoint,
+			Name:             *dirName,
+			Remote:           *remote,
+			PullRequest:      *prNumber,
+			Refresh:          *refresh,
+			Orphan:           *orphan,
+			Detach:           *detach,
+			Lock:             *lock,
+			LockReason:       *lockReason,
+			Sparse:           sparseDirs,
+			SparseProfiles:   sparseProfiles,
+			NoSubmodules:     *noSubmodules,
+			Carry:            *carry,
+			IncludeUntracked: *untracked,
+			KeepOnFailure:    *keepOnFailure,
+			Exec:             execStrings,
+			Input:            os.Stdin,
+		})
+		if err != nil {
 			fatal(err)
 		}
 	case "remove":
 		removeCmd := flag.NewFlagSet("remove", flag.ExitOnError)
-		removeCmd.Parse(os.Args[2:])
-		args := removeCmd.Args()
+		repos := removeCmd.String("repos", "", "remove the worktree from these registered repositories or groups (comma-separated)")
+		force := removeCmd.Bool("force", false, "remove the worktree even if it has uncommitted changes")
+		removeCmd.BoolVar(force, "f", false, "remove the worktree even if it has uncommitted changes (shorthand)")
+		args := parseInterspersed(removeCmd, os.Args[2:])
 
 		if len(args) < 1 {
+			if *repos != "" {
+				fmt.Fprintln(os.Stderr, "usage: wt remove --repos <names> <worktree>")
+				os.Exit(2)
+			}
 			// Show interactive selection if no path provided
-			if err := interactiveRemove(); err != nil {
+			if err := interactiveRemove(*force); err != nil {
 				fatal(err)
 			}
 			return
 		}
 
+		if *repos != "" {
+			if err := commands.RunRemoveCommand("", "git", args[0], splitRepos(*repos), *force); err != nil {
+				fatal(err)
+			}
+			return
+		}
+		if err := removeWorktree(newClient(), args[0], *force); err != nil {
+			fatal(err)
+		}
+	case "status":
+		statusCmd := flag.NewFlagSet("status", flag.ExitOnError)
+		jsonOutput := statusCmd.Bool("json", false, "output in JSON format")
+		statusCmd.BoolVar(jsonOutput, "j", false, "output in JSON format (shorthand)")
+		dirty := statusCmd.Bool("dirty", false, "only show worktrees with uncommitted changes")
+		unpushed := statusCmd.Bool("unpushed", false, "only show worktrees with commits that are not pushed")
+		allRepos := statusCmd.Bool("all-repos", false, "show the worktrees of every registered repository")
+		if err := statusCmd.Parse(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		if !*allRepos {
+			if err := ensureRepo(); err != nil {
+				fatal(err)
+			}
+		}
+		if err := runNewStatusCommand(*jsonOutput, *dirty, *unpushed, *allRepos); err != nil {
+			fatal(err)
+		}
+	case "sync":
+		syncCmd := flag.NewFlagSet("sync", flag.ExitOnError)
+		strategy := syncCmd.String("strategy", "", "how to update diverged branches: ff (skip them) or rebase (default from wt.sync.strategy)")
+		jobs := syncCmd.Int("jobs", 4, "number of worktrees to update at the same time")
+		noFetch := syncCmd.Bool("no-fetch", false, "update from the remote-tracking branches without fetching first")
+		if err := syncCmd.Parse(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		if err := ensureRepo(); err != nil {
+			fatal(err)
+		}
+		repoPath, err := gitTop()
+		if err != nil {
+			fatal(err)
+		}
+		if err := commands.RunSyncCommand(repoPath, "git", *strategy, *jobs, *noFetch); err != nil {
+			fatal(err)
+		}
+	case "adopt":
+		adoptCmd := flag.NewFlagSet("adopt", flag.ExitOnError)
+		all := adoptCmd.Bool("all", false, "adopt every worktree outside the worktrees directory")
+		dryRun := adoptCmd.Bool("dry-run", false, "show the planned moves without moving anything")
+		adoptCmd.BoolVar(dryRun, "n", false, "show the planned moves without moving anything (shorthand)")
+		yes := adoptCmd.Bool("yes", false, "move without asking for confirmation")
+		adoptCmd.BoolVar(yes, "y", false, "move without asking for confirmation (shorthand)")
+		args := parseInterspersed(adoptCmd, os.Args[2:])
+		if *all == (len(args) > 0) {
+			fmt.Fprintln(os.Stderr, "usage: wt adopt [-n] [-y] (--all | <worktree>...)")
+			os.Exit(2)
+		}
+		if err := ensureRepo(); err != nil {
+			fatal(err)
+		}
+		repoPath, err := gitTop()
+		if err != nil {
+			fatal(err)
+		}
+		if err := commands.RunAdoptCommand(repoPath, "git", args, *all, *dryRun, *yes); err != nil {
+			fatal(err)
+		}
+	case "relocate":
+		relocateCmd := flag.NewFlagSet("relocate", flag.ExitOnError)
+		if err := relocateCmd.Parse(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		args := relocateCmd.Args()
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: wt relocate <new-root>")
+			os.Exit(2)
+		}
+		if err := ensureRepo(); err != nil {
+			fatal(err)
+		}
+		repoPath, err := gitTop()
+		if err != nil {
+			fatal(err)
+		}
+		if err := commands.RunRelocateCommand(repoPath, "git", args[0]); err != nil {
+			fatal(err)
+		}
+	case "clone":
+		cloneCmd := flag.NewFlagSet("clone", flag.ExitOnError)
+		args := parseInterspersed(cloneCmd, os.Args[2:])
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Fprintln(os.Stderr, "usage: wt clone <url> [<dir>]")
+			os.Exit(2)
+		}
+		dir := commands.CloneDirFromURL(args[0])
+		if len(args) == 2 {
+			dir = args[1]
+		}
+		if err := commands.RunCloneCommand("git", args[0], dir); err != nil {
+			fatal(err)
+		}
+	case "init":
+		initCmd := flag.NewFlagSet("init", flag.ExitOnError)
+		bareHub := initCmd.Bool("bare-hub", false, "create a bare repository with sibling worktrees")
+		args := parseInterspersed(initCmd, os.Args[2:])
+		if !*bareHub || len(args) > 1 {
+			fmt.Fprintln(os.Stderr, "usage: wt init --bare-hub [<dir>]")
+			os.Exit(2)
+		}
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		if err := commands.RunInitCommand("git", dir); err != nil {
+			fatal(err)
+		}
+	case "repo":
+		repoCmd := flag.NewFlagSet("repo", flag.ExitOnError)
+		name := repoCmd.String("name", "", "name to register the repository as (default: its directory name)")
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "usage: wt repo add [--name <name>] [<path>] | wt repo remove <name> | wt repo list")
+			os.Exit(2)
+		}
+		action := os.Args[2]
+		args := parseInterspersed(repoCmd, os.Args[3:])
+		target := ""
+		switch {
+		case action == "add" && len(args) <= 1:
+			if len(args) == 1 {
+				target = args[0]
+			}
+		case action == "remove" && len(args) == 1 && *name == "":
+			target = args[0]
+		case action == "list" && len(args) == 0 && *name == "":
+		default:
+			fmt.Fprintln(os.Stderr, "usage: wt repo add [--name <name>] [<path>] | wt repo remove <name> | wt repo list")
+			os.Exit(2)
+		}
+		repoPath := ""
+		if action == "add" && target == "" {
+			if err := ensureRepo(); err != nil {
+				fatal(err)
+			}
+			top, err := gitTop()
+			if err != nil {
+				fatal(err)
+			}
+			repoPath = top
+		}
+		if err := commands.RunRepoCommand(repoPath, "git", action, target, *name); err != nil {
+			fatal(err)
+		}
+	case "lock", "unlock":
+		lockCmd := flag.NewFlagSet(cmd, flag.ExitOnError)
+		reason := lockCmd.String("reason", "", "why the worktree is locked")
+		args := parseInterspersed(lockCmd, os.Args[2:])
+		if len(args) != 1 || (cmd == "unlock" && *reason != "") {
+			if cmd == "lock" {
+				fmt.Fprintln(os.Stderr, "usage: wt lock <worktree> [--reason <text>]")
+			} else {
+				fmt.Fprintln(os.Stderr, "usage: wt unlock <worktree>")
+			}
+			os.Exit(2)
+		}
 		if err := ensureRepo(); err != nil {
 			fatal(err)
 		}
-		if err := runGit("worktree", "remove", args[0]); err != nil {
+		repoPath, err := gitTop()
+		if err != nil {
+			fatal(err)
+		}
+		if err := commands.RunLockCommand(repoPath, "git", args[0], *reason, cmd == "unlock"); err != nil {
+			fatal(err)
+		}
+	case "move":
+		moveCmd := flag.NewFlagSet("move", flag.ExitOnError)
+		if err := moveCmd.Parse(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		args := moveCmd.Args()
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: wt move <worktree> <new-name>")
+			os.Exit(2)
+		}
+		if err := ensureRepo(); err != nil {
+			fatal(err)
+		}
+		repoPath, err := gitTop()
+		if err != nil {
+			fatal(err)
+		}
+		if err := commands.RunMoveCommand(repoPath, "git", args[0], args[1]); err != nil {
 			fatal(err)
 		}
 	case "prune":
+		pruneCmd := flag.NewFlagSet("prune", flag.ExitOnError)
+		dryRun := pruneCmd.Bool("dry-run", false, "show what would be pruned without pruning")
+		pruneCmd.BoolVar(dryRun, "n", false, "show what would be pruned without pruning (shorthand)")
+		expire := pruneCmd.String("expire", "", "only prune worktrees missing for longer than <time> (e.g. 2.weeks.ago)")
+		repair := pruneCmd.Bool("repair", false, "repair worktrees that were moved by hand without asking")
+		if err := pruneCmd.Parse(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		client := newClient()
+		opts := wt.PruneOptions{DryRun: *dryRun, Expire: *expire, Repair: *repair, Input: os.Stdin}
+		if _, err := client.Prune(context.Background(), opts); err != nil {
+			fatal(err)
+		}
+	case "sparse":
+		sparseCmd := flag.NewFlagSet("sparse", flag.ExitOnError)
+		var profiles stringList
+		sparseCmd.Var(&profiles, "profile", "also add or remove the directories of wt.sparse.<name> (repeatable)")
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "usage: wt sparse add|remove [--profile <name>] <dir>... | wt sparse list")
+			os.Exit(2)
+		}
+		action := os.Args[2]
+		args := parseInterspersed(sparseCmd, os.Args[3:])
+		switch action {
+		case "add", "remove":
+			if len(args) == 0 && len(profiles) == 0 {
+				fmt.Fprintf(os.Stderr, "usage: wt sparse %s [--profile <name>] <dir>...\n", action)
+				os.Exit(2)
+			}
+		case "list":
+		default:
+			fmt.Fprintln(os.Stderr, "usage: wt sparse add|remove [--profile <name>] <dir>... | wt sparse list")
+			os.Exit(2)
+		}
+		if err := ensureRepo(); err != nil {
+			fatal(err)
+		}
+		worktreePath, err := gitTop()
+		if err != nil {
+			fatal(err)
+		}
+		if err := commands.RunSparseCommand(worktreePath, "git", action, args, profiles); err != nil {
+			fatal(err)
+		}
+	case "gc":
+		gcCmd := flag.NewFlagSet("gc", flag.ExitOnError)
+		olderThan := gcCmd.String("older-than", "", "remove worktrees idle for longer than this age (e.g. 30d)")
+		report := gcCmd.Bool("report", false, "list candidates without removing them")
+		force := gcCmd.Bool("force", false, "also remove worktrees with uncommitted changes")
+		if err := gcCmd.Parse(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		var age *time.Duration // nil falls back to wt.gc.olderThan
+		if *olderThan != "" {
+			parsed, err := utils.ParseAge(*olderThan)
+			if err != nil {
+				fatal(err)
+			}
+			age = &parsed
+		}
+		if err := ensureRepo(); err != nil {
+			fatal(err)
+		}
+		repoPath, err := gitTop()
+		if err != nil {
+			fatal(err)
+		}
+		if err := commands.RunGCCommand(repoPath, "git", age, *report, *force); err != nil {
+			fatal(err)
+		}
+	case "doctor":
+		doctorCmd := flag.NewFlagSet("doctor", flag.ExitOnError)
+		jsonOutput := doctorCmd.Bool("j", false, "output results in JSON format")
+		doctorCmd.BoolVar(jsonOutput, "json", false, "output results in JSON format")
+		if err := doctorCmd.Parse(os.Args[2:]); err != nil {
+			fatal(err)
+		}
 		if err := ensureRepo(); err != nil {
 			fatal(err)
 		}
-		if err := runGit("worktree", "prune"); err != nil {
+		repoPath, err := gitTop()
+		if err != nil {
+			fatal(err)
+		}
+		if err := commands.RunDoctorCommand(repoPath, "git", *jsonOutput); err != nil {
 			fatal(err)
 		}
 	case "exec":
@@ -139,16 +503,41 @@ func main() {
 			fmt.Fprintln(os.Stderr, "usage: wt exec <command> [<args>...]")
 			os.Exit(2)
 		}
+		client := newClient()
+		if err := interactiveExec(client, args); err != nil {
+			var exitErr *wt.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.Code)
+			}
+			fatal(err)
+		}
+	case "serve":
+		serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+		socket := serveCmd.String("socket", "", "path of the unix socket to listen on")
+		interval := serveCmd.Duration("interval", 2*time.Second, "how often to check for worktree changes (0 disables)")
+		if err := serveCmd.Parse(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		if *socket == "" || serveCmd.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "usage: wt serve --socket <path> [--interval <duration>]")
+			os.Exit(2)
+		}
 		if err := ensureRepo(); err != nil {
 			fatal(err)
 		}
-		if err := interactiveExec(args); err != nil {
+		repoPath, err := gitTop()
+		if err != nil {
+			fatal(err)
+		}
+		if err := commands.RunServeCommand(repoPath, "git", *socket, *interval); err != nil {
 			fatal(err)
 		}
 	case "version":
 		versionCmd := flag.NewFlagSet("version", flag.ExitOnError)
 		jsonOutput := versionCmd.Bool("j", false, "Output version information in JSON format")
 		help := versionCmd.Bool("h", false, "Show help for version command")
+		verbose := versionCmd.Bool("v", false, "Also show the detected git version and capabilities")
+		versionCmd.BoolVar(verbose, "verbose", false, "Also show the detected git version and capabilities")
 		if err := versionCmd.Parse(os.Args[2:]); err != nil {
 			fatal(err)
 		}
@@ -156,7 +545,7 @@ func main() {
 			commands.PrintVersionHelp()
 			return
 		}
-		if err := commands.RunVersionCommand(versionCmd.Args(), *jsonOutput); err != nil {
+		if err := commands.RunVersionCommand("git", versionCmd.Args(), *jsonOutput, *verbose); err != nil {
 			fatal(err)
 		}
 	case "help", "-h", "--help":
@@ -177,70 +566,167 @@ Usage:
     -j, --json           Output in JSON format for programmatic use
     -f, --filter <name>  Filter worktrees by name pattern
     -b, --branch <name>  Filter worktrees by branch name
+    --all-repos          List the worktrees of every registered repository
   wt add [-b] [--exec <command>] <branch>   Add a worktree (use -b to create a new branch)
                           Worktrees are created in ../worktrees/<branchname>
                           Use --exec to run commands in the new worktree
-  wt remove [path]        Remove a worktree (interactive if no path specified)
-  wt exec <command>       Execute a command in a selected worktree
-  wt prune               Prune stale worktrees
-  wt version             Display version information
+                          A branch that only exists on a remote gets a local branch
+                          tracking it; use --remote <name> if several remotes have it
+                          Use --pr <number> [--refresh] to check out a GitHub pull
+                          request or GitLab merge request as branch pr/<number>
+                          Use --orphan <branch> for a branch without history,
+                          --detach <commit> for a throwaway checkout, and
+                          --lock [--reason <text>] to lock the new worktree
+                          Use --sparse <dir> (repeatable) or --sparse-profile <name>
+                          to only check out some directories
+                          The directory is named after the branch (feature/x -> feature-x)
+                          unless --name <dir> is given
+                          Submodules are initialized unless --no-submodules is given
+                          Use --carry [-u] to move uncommitted (and untracked) changes
+                          of the current worktree into the new one
+                          If any step fails, the new worktree and branch are removed
+                          again unless --keep-on-failure is given
+                          Use --repos <names> to create the same branch and worktree
+                          in several registered repositories or groups (wt.group.<name>);
+                          if one fails, the others are rolled back too
+  wt adopt [-n] [-y] (--all | <worktree>...)
+                          Move worktrees created elsewhere with 'git worktree add'
+                          into the worktrees directory, named like 'wt add' would
+                          -n, --dry-run prints the planned moves; locked worktrees
+                          are left alone
+  wt relocate <new-root>  Move every worktree in the worktrees directory to <new-root>,
+                          keeping their layout, and create new worktrees there
+                          Run it again to resume an interrupted relocation
+  wt clone <url> [<dir>]  Clone into a hub: a bare repository in <dir>/.bare with
+                          worktrees next to it, starting with the default branch
+  wt init --bare-hub [<dir>]
+                          Create an empty hub
+  wt remove [-f] [worktree]
+                          Remove a worktree (interactive if none specified)
+                          Locked worktrees are refused, and so are worktrees with
+                          uncommitted changes unless -f, --force is given
+                          --repos <names> removes it from several repositories at
+                          once, or from none if any is locked or has changes
+  wt exec <command>       Execute a command in a selected worktree, exiting with its status
+  wt status [--dirty] [--unpushed] [--all-repos] [-j]
+                          Show changes, ahead/behind, last commit, in-progress
+                          operations and locks of every worktree
+                          --all-repos covers every registered repository
+  wt repo add [--name <name>] [<path>] | wt repo remove <name> | wt repo list
+                          Manage the registry of repositories used by --all-repos
+                          and --repos
+                          Repositories are registered by 'wt add' automatically
+  wt sync [--strategy ff|rebase] [--jobs <n>] [--no-fetch]
+                          Fetch once, then update every clean worktree from its
+                          upstream (or the default branch). Dirty worktrees are
+                          skipped; diverged branches are rebased with --strategy rebase
+  wt lock <worktree> [--reason <text>]
+                          Lock a worktree so that it is not pruned, moved or removed
+  wt unlock <worktree>    Unlock a locked worktree
+  wt move <worktree> <new-name>
+                          Rename a worktree's branch and move its directory to match
+                          <worktree> is a path, directory name or branch
+  wt prune [-n] [--expire <time>] [--repair]
+                          Prune stale worktrees, listing each one and why
+                          -n, --dry-run shows what would be pruned
+                          Worktrees moved by hand are repaired instead of pruned
+  wt sparse add|remove [--profile <name>] <dir>...
+                          Check out more or fewer directories in a sparse worktree
+  wt sparse list          List the directories checked out in the current worktree
+  wt gc [--older-than <age>] [--report] [--force]
+                          Remove worktrees idle for longer than <age> (e.g. 30d)
+                          Locked worktrees are never removed
+  wt doctor [-j]         Diagnose git, configuration and worktree problems
+                          Exits non-zero when an error is found
+  wt serve --socket <path> [--interval <duration>]
+                          Serve list, status, add, remove, exec and switch as JSON-RPC 2.0
+                          on a unix socket for editor plugins, notifying clients
+                          when the worktrees change
+  wt version [-v]        Display version information
+                          -v also shows the git version and which git features wt can use
   wt help                Show this help
 `)
 }
 
-func fatal(err error) {
-	fmt.Fprint(os.Stderr, "error: ", err, "\n")
-	os.Exit(1)
-}
+// stringList is a flag.Value that collects every occurrence of a repeatable flag
+type stringList []string
 
-func runGit(args ...string) error {
-	cmd := exec.Command("git", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+func (s *stringList) String() string {
+	return strings.Join(*s, ", ")
 }
 
-func ensureRepo() error {
-	_, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
-	if err != nil {
-		return errors.New("not a git repository (or any of the parent directories)")
-	}
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
 	return nil
 }
 
-type worktreeEntry struct {
-	Path   string
-	Head   string
-	Branch string
+// splitRepos splits the comma-separated value of --repos into names
+func splitRepos(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' })
 }
 
-func listWorktrees() error {
-	out, err := exec.Command("git", "worktree", "list", "--porcelain").Output()
-	if err != nil {
-		return err
+// parseInterspersed parses args with fs, allowing flags to appear after
+// positional arguments (e.g. "wt add -b feature -e 'npm ci'"), and returns
+// the positional arguments. A "--" ends flag parsing.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			fatal(err)
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional
+		}
+		if len(args) > len(rest) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...)
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
 	}
-	entries, err := parsePorcelain(out)
+}
+
+// newClient returns a client for the repository of the current directory, or
+// exits if there is none
+func newClient() *wt.Client {
+	client, err := wt.NewClient("", wt.ClientOptions{Output: os.Stdout})
 	if err != nil {
-		return err
+		fatal(err)
 	}
-	// print simple table
-	fmt.Printf("%-40s %-12s %s\n", "PATH", "HEAD", "BRANCH")
-	for _, e := range entries {
-		branch := e.Branch
-		if branch == "" {
-			branch = "(detached)"
-		}
-		head := e.Head
-		if head == "" {
-			head = "(none)"
-		} else if len(head) > 12 {
-			head = head[:12]
-		}
-		fmt.Printf("%-40s %-12s %s\n", e.Path, head, branch)
+	return client
+}
+
+// removeWorktree removes a worktree, pointing to --force if it has changes
+func removeWorktree(client *wt.Client, worktree string, force bool) error {
+	_, err := client.Remove(context.Background(), worktree, wt.RemoveOptions{Force: force})
+	var dirtyErr *wt.DirtyError
+	if errors.As(err, &dirtyErr) {
+		return fmt.Errorf("%w; use --force to remove it anyway", err)
+	}
+	return err
+}
+
+func fatal(err error) {
+	fmt.Fprint(os.Stderr, "error: ", err, "\n")
+	os.Exit(1)
+}
+
+func ensureRepo() error {
+	if _, err := gitTop(); err != nil {
+		return errors.New("not a git repository (or any of the parent directories)")
 	}
 	return nil
 }
 
+type worktreeEntry struct {
+	Bare       bool
+	Path       string
+	Head       string
+	Branch     string
+	Locked     bool
+	LockReason string
+}
+
 func parsePorcelain(b []byte) ([]worktreeEntry, error) {
 	scanner := bufio.NewScanner(bytes.NewReader(b))
 	var entries []worktreeEntry
@@ -266,6 +752,11 @@ func parsePorcelain(b []byte) ([]worktreeEntry, error) {
 			cur.Head = strings.TrimPrefix(line, "HEAD ")
 		} else if strings.HasPrefix(line, "branch ") {
 			cur.Branch = strings.TrimPrefix(line, "branch ")
+		} else if line == "bare" {
+			cur.Bare = true
+		} else if line == "locked" || strings.HasPrefix(line, "locked ") {
+			cur.Locked = true
+			cur.LockReason = strings.TrimSpace(strings.TrimPrefix(line, "locked"))
 		}
 	}
 	if seen {
@@ -277,15 +768,26 @@ func parsePorcelain(b []byte) ([]worktreeEntry, error) {
 	return entries, nil
 }
 
+// gitTop returns the root of the current worktree. Outside a worktree of a bare
+// repository, such as in the hub directory created by 'wt clone', it returns the
+// repository itself.
 func gitTop() (string, error) {
 	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
-	if err != nil {
+	if err == nil {
+		return strings.TrimSpace(string(out)), nil
+	}
+	bare, bareErr := exec.Command("git", "rev-parse", "--is-bare-repository", "--absolute-git-dir").Output()
+	if bareErr != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(out)), nil
+	if lines := strings.Split(strings.TrimSpace(string(bare)), "\n"); len(lines) == 2 && lines[0] == "true" {
+		return lines[1], nil
+	}
+	return "", err
 }
 
-func interactiveRemove() error {
+func interactiveRemove(force bool) error {
+	client := newClient()
 	entries, err := getWorktreeEntries()
 	if err != nil {
 		return err
@@ -299,7 +801,7 @@ func interactiveRemove() error {
 		if err != nil {
 			continue
 		}
-		if e.Path == topLevel {
+		if e.Path == topLevel || e.Bare {
 			continue
 		}
 		removableEntries = append(removableEntries, e)
@@ -317,7 +819,14 @@ func interactiveRemove() error {
 		if branch == "" {
 			branch = "(detached)"
 		}
-		fmt.Printf("%d: %s (%s)\n", i+1, e.Path, branch)
+		lock := ""
+		if e.Locked {
+			lock = " [locked]"
+			if e.LockReason != "" {
+				lock = " [locked: " + e.LockReason + "]"
+			}
+		}
+		fmt.Printf("%d: %s (%s)%s\n", i+1, e.Path, branch, lock)
 	}
 
 	reader := bufio.NewReader(os.Stdin)
@@ -343,10 +852,10 @@ func interactiveRemove() error {
 	selectedEntry := removableEntries[selected-1]
 	fmt.Printf("Removing worktree: %s\n", selectedEntry.Path)
 
-	return runGit("worktree", "remove", selectedEntry.Path)
+	return removeWorktree(client, selectedEntry.Path, force)
 }
 
-func interactiveExec(commandArgs []string) error {
+func interactiveExec(client *wt.Client, commandArgs []string) error {
 	entries, err := getWorktreeEntries()
 	if err != nil {
 		return err
@@ -360,7 +869,7 @@ func interactiveExec(commandArgs []string) error {
 		if err != nil {
 			continue
 		}
-		if e.Path == topLevel {
+		if e.Path == topLevel || e.Bare {
 			continue
 		}
 		execEntries = append(execEntries, e)
@@ -404,14 +913,8 @@ func interactiveExec(commandArgs []string) error {
 	selectedEntry := execEntries[selected-1]
 	fmt.Printf("Executing command in worktree: %s\n", selectedEntry.Path)
 
-	// Change to the worktree directory and execute the command
-	cmd := exec.Command(commandArgs[0], commandArgs[1:]...)
-	cmd.Dir = selectedEntry.Path
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-
-	return cmd.Run()
+	opts := wt.ExecOptions{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+	return client.Exec(context.Background(), selectedEntry.Path, commandArgs, opts)
 }
 
 func getWorktreeEntries() ([]worktreeEntry, error) {
@@ -421,52 +924,3 @@ func getWorktreeEntries() ([]worktreeEntry, error) {
 	}
 	return parsePorcelain(out)
 }
-
-// defaultRef returns the repository's default branch ref (e.g. "origin/main" or "main").
-// It first tries to read symbolic-ref "refs/remotes/origin/HEAD" and fallbacks to trying
-// to parse `git remote show origin` or finally uses the current branch.
-func defaultRef() (string, error) {
-	// Try: git symbolic-ref refs/remotes/origin/HEAD
-	out, err := exec.Command("git", "symbolic-ref", "refs/remotes/origin/HEAD").Output()
-	if err == nil {
-		ref := strings.TrimSpace(string(out))
-		// refs/remotes/origin/HEAD -> refs/remotes/origin/main => use origin/main
-		if strings.HasPrefix(ref, "refs/remotes/") {
-			return strings.TrimPrefix(ref, "refs/remotes/"), nil
-		}
-		return ref, nil
-	}
-
-	// As a fallback, try `git remote show origin` and parse "HEAD branch: <name>"
-	out2, err2 := exec.Command("git", "remote", "show", "origin").Output()
-	if err2 == nil {
-		scanner := bufio.NewScanner(bytes.NewReader(out2))
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if strings.HasPrefix(line, "HEAD branch:") {
-				parts := strings.SplitN(line, ":", 2)
-				if len(parts) == 2 {
-					branch := strings.TrimSpace(parts[1])
-					// prefer origin/<branch>
-					return "origin/" + branch, nil
-				}
-			}
-		}
-	}
-
-	// Try to get current branch
-	out3, err3 := exec.Command("git", "symbolic-ref", "--short", "HEAD").Output()
-	if err3 == nil {
-		branch := strings.TrimSpace(string(out3))
-		return branch, nil
-	}
-
-	// Last resort: use 'main' or 'master' if they exist
-	for _, cand := range []string{"main", "master"} {
-		if err := exec.Command("git", "show-ref", "--verify", "refs/heads/"+cand).Run(); err == nil {
-			return cand, nil
-		}
-	}
-
-	return "", errors.New("could not determine default branch")
-}
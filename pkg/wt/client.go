@@ -0,0 +1,308 @@
+package wt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/smoerfugl/wt/internal/commands"
+	"github.com/smoerfugl/wt/internal/models"
+	"github.com/smoerfugl/wt/internal/services"
+	"github.com/smoerfugl/wt/internal/utils"
+)
+
+// Client manages the worktrees of one repository. It is safe for concurrent
+// use, but like the CLI it does not stop two calls from changing the same
+// worktree at once.
+type Client struct {
+	repoPath   string
+	gitService *services.GitService
+	out        io.Writer
+}
+
+// ClientOptions configures a Client
+type ClientOptions struct {
+	GitPath string    // The git executable; "git" if empty
+	Output  io.Writer // Where progress is reported, as the CLI prints it; discarded if nil
+}
+
+// ListOptions selects the worktrees returned by List
+type ListOptions struct {
+	Filter string // Only worktrees whose directory name contains this
+	Branch string // Only worktrees on this branch
+}
+
+// StatusOptions selects the worktrees returned by Status
+type StatusOptions struct {
+	Dirty    bool // Only worktrees with uncommitted changes or untracked files
+	Unpushed bool // Only worktrees whose branch has commits that are not pushed
+}
+
+// AddOptions configures Add. Branch is required unless PullRequest is set.
+type AddOptions struct {
+	Branch           string    // Branch or commit to check out, or the name of the new branch
+	Create           bool      // Create Branch, starting at StartPoint
+	StartPoint       string    // With Create, where the branch starts; HEAD if empty
+	Name             string    // Directory name instead of one derived from Branch
+	Remote           string    // Remote to track when Branch only exists on remotes
+	PullRequest      int       // Check out this pull/merge request as branch pr/<number>
+	Refresh          bool      // With PullRequest, update an existing pull request worktree
+	Orphan           bool      // Create Branch without history
+	Detach           bool      // Check out Branch, a commit, with a detached HEAD
+	Lock             bool      // Lock the new worktree
+	LockReason       string    // With Lock, why the worktree is locked
+	Sparse           []string  // Only check out these directories
+	SparseProfiles   []string  // Only check out the directories of these wt.sparse.<name> profiles
+	NoSubmodules     bool      // Do not initialize submodules
+	Carry            bool      // Move uncommitted changes of the repository's worktree into the new one
+	IncludeUntracked bool      // With Carry, also move untracked files
+	KeepOnFailure    bool      // Keep what was created when a step fails instead of rolling back
+	Exec             []string  // Shell commands to run in the new worktree
+	Input            io.Reader // Answers to prompts; nil fails where the CLI would ask
+}
+
+// RemoveOptions configures Remove
+type RemoveOptions struct {
+	Force bool // Remove the worktree even if it has uncommitted changes or untracked files
+}
+
+// PruneOptions configures Prune
+type PruneOptions struct {
+	DryRun bool      // Only report what would be pruned
+	Expire string    // Only prune worktrees missing for longer than this, e.g. "2.weeks.ago"
+	Repair bool      // Repair worktrees that were moved by hand instead of pruning them
+	Input  io.Reader // Where to ask whether to repair moved worktrees; nil prunes them unless Repair is set
+}
+
+// ExecOptions configures Exec. Nil streams are connected to the null device.
+type ExecOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Env    []string // Added to the environment of the current process
+}
+
+// NewClient returns a client for the repository containing dir, or the current
+// directory if dir is empty. In a hub created by 'wt clone', the client manages
+// the bare repository's worktrees.
+func NewClient(dir string, opts ClientOptions) (*Client, error) {
+	gitService := services.NewGitService(opts.GitPath)
+	if dir == "" {
+		dir = "."
+	}
+	repoPath, err := gitService.GetTopLevel(dir)
+	if err != nil {
+		return nil, ErrNotRepository
+	}
+	out := opts.Output
+	if out == nil {
+		out = io.Discard
+	}
+	return &Client{repoPath: repoPath, gitService: gitService, out: out}, nil
+}
+
+// RepoPath returns the root of the worktree the client was created in, or the
+// bare repository of a hub
+func (c *Client) RepoPath() string {
+	return c.repoPath
+}
+
+// List returns the worktrees of the repository, the main worktree first
+func (c *Client) List(ctx context.Context, opts ListOptions) ([]Worktree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	lc := commands.NewListCommand(c.gitService.WithContext(ctx))
+	lc.SetFilter(opts.Filter)
+	lc.SetBranch(opts.Branch)
+	worktrees, err := lc.Worktrees(c.repoPath)
+	if err != nil {
+		return nil, clientError(ctx, err)
+	}
+	return newWorktrees(worktrees), nil
+}
+
+// Status returns the status of every worktree except a hub's bare repository
+func (c *Client) Status(ctx context.Context, opts StatusOptions) ([]Status, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sc := commands.NewStatusCommand(c.gitService.WithContext(ctx))
+	sc.SetDirtyOnly(opts.Dirty)
+	sc.SetUnpushedOnly(opts.Unpushed)
+	statuses, err := sc.Statuses(c.repoPath)
+	if err != nil {
+		return nil, clientError(ctx, err)
+	}
+	result := make([]Status, 0, len(statuses))
+	for _, s := range statuses {
+		result = append(result, newStatus(s))
+	}
+	return result, nil
+}
+
+// Add creates a worktree like 'wt add' and returns it. If a step fails, what
+// was created is removed again unless KeepOnFailure is set. Like the CLI, it
+// registers the repository for 'wt repo' in the global git config.
+func (c *Client) Add(ctx context.Context, opts AddOptions) (*Worktree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	gitService := c.gitService.WithContext(ctx)
+	ac := commands.NewAddCommand(gitService)
+	ac.SetOutput(c.out)
+	ac.SetInput(opts.Input)
+	ac.SetCreateBranch(opts.Create)
+	ac.SetBranchName(opts.Branch)
+	ac.SetStartPoint(opts.StartPoint)
+	ac.SetDirName(opts.Name)
+	ac.SetRemote(opts.Remote)
+	ac.SetPullRequest(opts.PullRequest, opts.Refresh)
+	ac.SetOrphan(opts.Orphan)
+	ac.SetDetach(opts.Detach)
+	ac.SetLock(opts.Lock, opts.LockReason)
+	ac.SetSparse(opts.Sparse, opts.SparseProfiles)
+	ac.SetNoSubmodules(opts.NoSubmodules)
+	ac.SetCarry(opts.Carry, opts.IncludeUntracked)
+	ac.SetKeepOnFailure(opts.KeepOnFailure)
+	for _, command := range opts.Exec {
+		cmd := utils.NewCommand("sh", []string{"-c", command})
+		if err := cmd.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid exec command: %w", err)
+		}
+		ac.AddExecCommand(cmd)
+	}
+
+	if err := ac.Execute(c.repoPath); err != nil {
+		return nil, clientError(ctx, err)
+	}
+	worktrees, err := gitService.GetWorktrees(c.repoPath)
+	if err != nil {
+		return nil, clientError(ctx, err)
+	}
+	wt, err := commands.FindWorktree(worktrees, ac.WorktreePath())
+	if err != nil {
+		return nil, &NotFoundError{Worktree: ac.WorktreePath()}
+	}
+	added := newWorktree(wt)
+	return &added, nil
+}
+
+// Remove removes a worktree, given by path, directory name or branch, and
+// returns it. Locked worktrees are refused with a *LockedError, and worktrees
+// with changes with a *DirtyError unless Force is set.
+func (c *Client) Remove(ctx context.Context, worktree string, opts RemoveOptions) (*Worktree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	gitService := c.gitService.WithContext(ctx)
+	wt, err := c.find(gitService, worktree)
+	if err != nil {
+		return nil, clientError(ctx, err)
+	}
+	switch {
+	case wt.IsCurrent || wt.IsBare:
+		return nil, fmt.Errorf("%s is the main worktree and cannot be removed", wt.Path)
+	case wt.IsLocked:
+		return nil, &LockedError{Path: wt.Path, Name: wt.Name, Reason: wt.LockReason}
+	case !opts.Force && !wt.IsPrunable():
+		status, err := gitService.GetWorktreeStatus(wt.Path)
+		if err != nil {
+			return nil, clientError(ctx, err)
+		}
+		if status.IsDirty() {
+			return nil, &DirtyError{Path: wt.Path}
+		}
+	}
+
+	rc := commands.NewRemoveCommand(gitService)
+	rc.SetOutput(c.out)
+	rc.SetWorktree(wt.Path)
+	rc.SetForce(opts.Force)
+	if err := rc.Execute(c.repoPath); err != nil {
+		return nil, clientError(ctx, err)
+	}
+	removed := newWorktree(wt)
+	return &removed, nil
+}
+
+// Prune removes the administrative files of worktrees whose directories are
+// gone. Worktrees that were moved by hand are repaired instead if Repair is
+// set or the answer read from Input is yes.
+func (c *Client) Prune(ctx context.Context, opts PruneOptions) (*PruneResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pc := commands.NewPruneCommand(c.gitService.WithContext(ctx))
+	pc.SetOutput(c.out)
+	pc.SetInput(opts.Input)
+	pc.SetDryRun(opts.DryRun)
+	pc.SetExpire(opts.Expire)
+	pc.SetRepair(opts.Repair)
+	if err := pc.Execute(c.repoPath); err != nil {
+		return nil, clientError(ctx, err)
+	}
+	return &PruneResult{Pruned: newWorktrees(pc.Pruned()), Repaired: pc.Repaired()}, nil
+}
+
+// Exec runs command in a worktree, given by path, directory name or branch,
+// with WT_WORKTREE and WT_BRANCH set. A non-zero exit status is returned as an
+// *ExitError. The command is killed when ctx is done.
+func (c *Client) Exec(ctx context.Context, worktree string, command []string, opts ExecOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(command) == 0 {
+		return errors.New("no command given")
+	}
+	wt, err := c.find(c.gitService.WithContext(ctx), worktree)
+	if err != nil {
+		return clientError(ctx, err)
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = wt.Path
+	cmd.Env = append(os.Environ(), opts.Env...)
+	cmd.Env = append(cmd.Env,
+		fmt.Sprintf("WT_WORKTREE=%s", wt.Path),
+		fmt.Sprintf("WT_BRANCH=%s", wt.Branch),
+	)
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	err = cmd.Run()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Path: wt.Path, Code: exitErr.ExitCode()}
+	}
+	return err
+}
+
+// find returns the worktree that arg refers to
+func (c *Client) find(gitService *services.GitService, arg string) (models.Worktree, error) {
+	worktrees, err := gitService.GetWorktrees(c.repoPath)
+	if err != nil {
+		return models.Worktree{}, fmt.Errorf("failed to get worktrees: %w", err)
+	}
+	wt, err := commands.FindWorktree(worktrees, arg)
+	if err != nil {
+		return models.Worktree{}, &NotFoundError{Worktree: arg}
+	}
+	return wt, nil
+}
+
+// clientError returns the context's error if err was caused by it being done,
+// as git killed by a cancelled context only reports the signal. Otherwise it
+// returns err with a failed git command exposed as a *GitError.
+func clientError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return newGitFailure(err)
+}
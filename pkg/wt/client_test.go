@@ -0,0 +1,164 @@
+package wt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestClient creates a repository with one commit in a temporary directory
+// and returns a client for it. The global git config is replaced by an empty one.
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed in test environment")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	if err := os.Mkdir(repoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, repoDir, "init")
+	runTestGit(t, repoDir, "config", "user.email", "test@example.com")
+	runTestGit(t, repoDir, "config", "user.name", "Test")
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, repoDir, "add", ".")
+	runTestGit(t, repoDir, "commit", "-m", "init")
+
+	client, err := NewClient(filepath.Join(repoDir, "."), ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// runTestGit runs git in dir and fails the test on error
+func runTestGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+func TestNewClientOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed in test environment")
+	}
+	t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+	if _, err := NewClient(t.TempDir(), ClientOptions{}); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("NewClient outside a repository: err = %v, want ErrNotRepository", err)
+	}
+}
+
+func TestClientWorktreeLifecycle(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	added, err := client.Add(ctx, AddOptions{Branch: "feature/x", Create: true, Exec: []string{"touch marker"}})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if added.Name != "feature-x" || added.Branch != "feature/x" || added.Mode != ModeBranch || added.Main {
+		t.Errorf("Add returned %+v", added)
+	}
+
+	worktrees, err := client.List(ctx, ListOptions{Branch: "feature/x"})
+	if err != nil || len(worktrees) != 1 || worktrees[0].Path != added.Path {
+		t.Errorf("List = %+v, %v", worktrees, err)
+	}
+
+	statuses, err := client.Status(ctx, StatusOptions{Dirty: true})
+	if err != nil || len(statuses) != 1 || statuses[0].Untracked != 1 || !statuses[0].Dirty() {
+		t.Errorf("Status = %+v, %v", statuses, err)
+	}
+
+	var stdout bytes.Buffer
+	err = client.Exec(ctx, "feature-x", []string{"sh", "-c", `echo "$WT_BRANCH"; exit 3`}, ExecOptions{Stdout: &stdout})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 || strings.TrimSpace(stdout.String()) != "feature/x" {
+		t.Errorf("Exec: err = %v, stdout = %q", err, stdout.String())
+	}
+
+	// The marker left by the exec command makes the worktree dirty
+	var dirtyErr *DirtyError
+	if _, err := client.Remove(ctx, "feature/x", RemoveOptions{}); !errors.As(err, &dirtyErr) {
+		t.Fatalf("Remove of a dirty worktree: err = %v, want *DirtyError", err)
+	}
+	if _, err := client.Remove(ctx, "feature/x", RemoveOptions{Force: true}); err != nil {
+		t.Fatalf("Remove with Force: %v", err)
+	}
+	if _, err := os.Stat(added.Path); !os.IsNotExist(err) {
+		t.Errorf("%s still exists", added.Path)
+	}
+}
+
+func TestClientTypedErrors(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var notFound *NotFoundError
+	if _, err := client.Remove(ctx, "missing", RemoveOptions{}); !errors.As(err, &notFound) || notFound.Worktree != "missing" {
+		t.Errorf("Remove of a missing worktree: err = %v, want *NotFoundError", err)
+	}
+	if err := client.Exec(ctx, "missing", []string{"true"}, ExecOptions{}); !errors.As(err, &notFound) {
+		t.Errorf("Exec in a missing worktree: err = %v, want *NotFoundError", err)
+	}
+
+	added, err := client.Add(ctx, AddOptions{Branch: "locked", Create: true, Lock: true, LockReason: "in use"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lockedErr *LockedError
+	if _, err := client.Remove(ctx, added.Path, RemoveOptions{Force: true}); !errors.As(err, &lockedErr) || lockedErr.Reason != "in use" {
+		t.Errorf("Remove of a locked worktree: err = %v, want *LockedError", err)
+	}
+
+	var gitErr *GitError
+	if _, err := client.Add(ctx, AddOptions{Branch: "new", Create: true, StartPoint: "no-such-commit"}); !errors.As(err, &gitErr) || len(gitErr.Args) == 0 || gitErr.Output == "" {
+		t.Errorf("Add from a missing start point: err = %v, want *GitError with git's arguments and output", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.List(cancelled, ListOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("List with a cancelled context: err = %v", err)
+	}
+}
+
+func TestClientPrune(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	added, err := client.Add(ctx, AddOptions{Branch: "gone", Create: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(added.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.Prune(ctx, PruneOptions{DryRun: true})
+	if err != nil || len(result.Pruned) != 1 || result.Pruned[0].Path != added.Path {
+		t.Fatalf("Prune dry run = %+v, %v", result, err)
+	}
+	if worktrees, _ := client.List(ctx, ListOptions{}); len(worktrees) != 2 {
+		t.Errorf("dry run pruned the worktree")
+	}
+
+	if _, err := client.Prune(ctx, PruneOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if worktrees, _ := client.List(ctx, ListOptions{}); len(worktrees) != 1 {
+		t.Errorf("List after Prune = %+v", worktrees)
+	}
+}
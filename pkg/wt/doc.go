@@ -0,0 +1,25 @@
+// Package wt manages the worktrees of a git repository the way the wt command
+// does, for Go programs that would otherwise run the CLI.
+//
+// A Client is bound to one repository. Its methods take a context, whose
+// cancellation stops the git processes they run, and an options struct whose
+// zero value gives the CLI's defaults:
+//
+//	client, err := wt.NewClient("/path/to/repo", wt.ClientOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	worktree, err := client.Add(ctx, wt.AddOptions{Branch: "feature/x", Create: true})
+//	if err != nil {
+//		return err
+//	}
+//	err = client.Exec(ctx, worktree.Path, []string{"make", "test"}, wt.ExecOptions{Stdout: os.Stdout})
+//
+// Failures that callers are likely to handle are returned as typed errors:
+// ErrNotRepository, *NotFoundError, *LockedError, *DirtyError, *ExitError and,
+// for a git command that failed, *GitError. Use errors.Is and errors.As to
+// check for them, as they may be wrapped.
+//
+// Configuration such as wt.root, wt.copy, wt.link and wt.sparse.<name> is read
+// from git config exactly as the CLI reads it.
+package wt
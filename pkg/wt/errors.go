@@ -0,0 +1,97 @@
+package wt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/smoerfugl/wt/internal/services"
+)
+
+// ErrNotRepository is returned by NewClient for a directory outside any git repository
+var ErrNotRepository = errors.New("not a git repository (or any of the parent directories)")
+
+// GitError is a git command that failed. Output holds what git wrote to
+// standard error.
+type GitError struct {
+	Args   []string // The arguments git was run with
+	Output string
+	Err    error // Usually an *exec.ExitError
+}
+
+func (e *GitError) Error() string {
+	return fmt.Sprintf("git %s: %v (output: %s)", strings.Join(e.Args, " "), e.Err, e.Output)
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// gitFailure is an error whose cause is a failed git command. It keeps the
+// message and wrapped errors of the original and exposes the cause as a *GitError.
+type gitFailure struct {
+	err error
+	git *GitError
+}
+
+func (e *gitFailure) Error() string {
+	return e.err.Error()
+}
+
+func (e *gitFailure) Unwrap() []error {
+	return []error{e.git, e.err}
+}
+
+// newGitFailure converts the failed git command behind err, if there is one
+func newGitFailure(err error) error {
+	var gitErr *services.GitError
+	if !errors.As(err, &gitErr) {
+		return err
+	}
+	return &gitFailure{err: err, git: &GitError{Args: gitErr.Args, Output: gitErr.Output, Err: gitErr.Err}}
+}
+
+// NotFoundError is returned when no worktree matches the path, directory name
+// or branch a method was given
+type NotFoundError struct {
+	Worktree string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no worktree named %q (see 'wt list')", e.Worktree)
+}
+
+// LockedError is returned when removing a locked worktree
+type LockedError struct {
+	Path   string
+	Name   string
+	Reason string // Empty if the lock has no reason
+}
+
+func (e *LockedError) Error() string {
+	lock := "locked"
+	if e.Reason != "" {
+		lock = "locked: " + e.Reason
+	}
+	return fmt.Sprintf("worktree %s is %s; run 'wt unlock %s' first", e.Path, lock, e.Name)
+}
+
+// DirtyError is returned when removing a worktree with uncommitted changes or
+// untracked files without RemoveOptions.Force
+type DirtyError struct {
+	Path string
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("worktree %s has uncommitted changes or untracked files", e.Path)
+}
+
+// ExitError is returned by Exec when the command exits with a non-zero status
+type ExitError struct {
+	Path string // The worktree the command ran in
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command in %s exited with status %d", e.Path, e.Code)
+}
@@ -0,0 +1,124 @@
+package wt
+
+import (
+	"time"
+
+	"github.com/smoerfugl/wt/internal/models"
+)
+
+// Mode is how a worktree's HEAD is set up
+type Mode string
+
+// Worktree modes
+const (
+	ModeBranch   Mode = "branch"   // On a branch
+	ModeDetached Mode = "detached" // On a commit without a branch
+	ModeOrphan   Mode = "orphan"   // On a branch without commits
+	ModeBare     Mode = "bare"     // The bare repository of a hub, which has no files
+)
+
+// Worktree is a worktree of the repository
+type Worktree struct {
+	Name       string `json:"name"` // Directory name
+	Path       string `json:"path"`
+	Branch     string `json:"branch,omitempty"` // Empty when detached
+	Commit     string `json:"commit,omitempty"`
+	Mode       Mode   `json:"mode"`
+	Main       bool   `json:"main"` // The main worktree, or the bare repository of a hub
+	Locked     bool   `json:"locked"`
+	LockReason string `json:"lock_reason,omitempty"`
+	Prunable   string `json:"prunable,omitempty"` // Why git would prune the worktree; empty if it would not
+}
+
+// Status is the state of a worktree's files and branch, as shown by 'wt status'
+type Status struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Branch     string `json:"branch"`
+	Locked     bool   `json:"locked"`
+	LockReason string `json:"lock_reason,omitempty"`
+	Prunable   string `json:"prunable,omitempty"`
+	Missing    bool   `json:"missing,omitempty"` // The directory is gone, e.g. a locked worktree on an unplugged disk
+
+	Staged    int `json:"staged"`    // Files with staged changes
+	Modified  int `json:"modified"`  // Files with unstaged changes
+	Untracked int `json:"untracked"` // Untracked files
+	Conflicts int `json:"conflicts"` // Files with merge conflicts
+
+	Upstream string `json:"upstream,omitempty"` // e.g. origin/main
+	Ahead    int    `json:"ahead"`              // Commits not on the upstream
+	Behind   int    `json:"behind"`             // Upstream commits not on the branch
+
+	LastCommitSubject string     `json:"last_commit_subject,omitempty"`
+	LastCommitTime    *time.Time `json:"last_commit_time,omitempty"` // nil on a branch without commits
+
+	Operation string `json:"operation,omitempty"` // In-progress rebase, merge, cherry-pick, revert, bisect or am
+}
+
+// Dirty reports whether the worktree has uncommitted changes or untracked files
+func (s *Status) Dirty() bool {
+	return s.Staged+s.Modified+s.Untracked+s.Conflicts > 0
+}
+
+// Unpushed reports whether the branch has commits its upstream lacks, or has no
+// upstream at all. Detached worktrees are never unpushed.
+func (s *Status) Unpushed() bool {
+	if s.Branch == "" || s.LastCommitTime == nil {
+		return false
+	}
+	return s.Upstream == "" || s.Ahead > 0
+}
+
+// PruneResult is what Prune did, or would do with PruneOptions.DryRun
+type PruneResult struct {
+	Pruned   []Worktree // Worktrees whose administrative files were removed
+	Repaired []string   // New paths of worktrees that were moved by hand and repaired
+}
+
+// newWorktree converts a worktree of the internal model
+func newWorktree(wt models.Worktree) Worktree {
+	info := wt.Info()
+	return Worktree{
+		Name:       info.Name,
+		Path:       info.Path,
+		Branch:     info.Branch,
+		Commit:     info.Commit,
+		Mode:       Mode(info.Mode),
+		Main:       info.Main,
+		Locked:     info.Locked,
+		LockReason: info.LockReason,
+		Prunable:   info.Prunable,
+	}
+}
+
+// newWorktrees converts worktrees of the internal model; none is an empty list
+func newWorktrees(worktrees []models.Worktree) []Worktree {
+	result := make([]Worktree, 0, len(worktrees))
+	for _, wt := range worktrees {
+		result = append(result, newWorktree(wt))
+	}
+	return result
+}
+
+// newStatus converts a status of the internal model
+func newStatus(s *models.WorktreeStatus) Status {
+	return Status{
+		Name:              s.Name,
+		Path:              s.Path,
+		Branch:            s.Branch,
+		Locked:            s.Locked,
+		LockReason:        s.LockReason,
+		Prunable:          s.Prunable,
+		Missing:           s.Missing,
+		Staged:            s.Staged,
+		Modified:          s.Modified,
+		Untracked:         s.Untracked,
+		Conflicts:         s.Conflicts,
+		Upstream:          s.Upstream,
+		Ahead:             s.Ahead,
+		Behind:            s.Behind,
+		LastCommitSubject: s.LastCommitSubject,
+		LastCommitTime:    s.LastCommitTime,
+		Operation:         s.Operation,
+	}
+}